@@ -0,0 +1,125 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import "fmt"
+
+// ErrReadOnly is returned, wrapped in a *FieldError, for a member that may
+// not be written by the document being unmarshaled when the RejectReadOnly
+// option is given. Its error object has the "403 Forbidden" status.
+var ErrReadOnly error = forbiddenError("member is read-only")
+
+// fieldAccess restricts the direction in which a member is transferred.
+type fieldAccess int
+
+const (
+	accessReadWrite fieldAccess = iota
+
+	// accessReadOnly members are marshaled but not accepted in requests.
+	accessReadOnly
+
+	// accessWriteOnly members are accepted in requests but never marshaled.
+	accessWriteOnly
+
+	// accessCreateOnly members are accepted in create requests only.
+	accessCreateOnly
+)
+
+// parseAccess returns the access option among the tag options of an
+// attribute or relation.
+func parseAccess(options []string) (fieldAccess, error) {
+	access := accessReadWrite
+	for _, opt := range options {
+		var a fieldAccess
+		switch opt {
+		case annotationReadOnly:
+			a = accessReadOnly
+		case annotationWriteOnly:
+			a = accessWriteOnly
+		case annotationCreateOnly:
+			a = accessCreateOnly
+		default:
+			continue
+		}
+
+		if access != accessReadWrite && access != a {
+			return accessReadWrite, fmt.Errorf("conflicting tag option %q", opt)
+		}
+		access = a
+	}
+
+	return access, nil
+}
+
+// operation is the kind of document being unmarshaled.
+type operation int
+
+const (
+	operationNone operation = iota
+	operationCreate
+	operationUpdate
+	operationResponse
+)
+
+// ForCreate marks the document being unmarshaled as the body of a create
+// request: members tagged readonly are ignored, or rejected with the
+// RejectReadOnly option.
+func ForCreate() Option {
+	return func(o *options) {
+		o.operation = operationCreate
+	}
+}
+
+// ForUpdate marks the document being unmarshaled as the body of an update
+// request: members tagged readonly or createonly are ignored, or rejected
+// with the RejectReadOnly option.
+func ForUpdate() Option {
+	return func(o *options) {
+		o.operation = operationUpdate
+	}
+}
+
+// ForResponse marks the document being unmarshaled as a response read by a
+// client: every member is written, including those tagged readonly or
+// createonly.
+func ForResponse() Option {
+	return func(o *options) {
+		o.operation = operationResponse
+	}
+}
+
+// RejectReadOnly makes the unmarshal functions return a *FieldError wrapping
+// ErrReadOnly for a member that the document being unmarshaled may not write,
+// instead of ignoring it.
+func RejectReadOnly() Option {
+	return func(o *options) {
+		o.rejectReadOnly = true
+	}
+}
+
+// readOnly reports whether the document being unmarshaled may not write the
+// member of field. Members tagged readonly are only written from responses
+// given by ForResponse, and createonly members from any document but an
+// update request.
+func (o *options) readOnly(field *fieldInfo) bool {
+	switch field.access {
+	case accessReadOnly:
+		return o.operation != operationResponse
+	case accessCreateOnly:
+		return o.operation == operationUpdate
+	}
+
+	return false
+}
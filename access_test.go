@@ -0,0 +1,189 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const accountDoc = `{
+	"data": {
+		"type": "accounts",
+		"attributes": {
+			"name": "Jane",
+			"email": "jane@example.com",
+			"password": "secret",
+			"created_at": "2016-08-17T08:27:12Z"
+		},
+		"relationships": {"manager": {"data": {"type": "accounts", "id": "2"}}}
+	}
+}`
+
+func TestAccess_marshal(t *testing.T) {
+	account := &Account{
+		ID:        1,
+		Name:      "Jane",
+		Email:     "jane@example.com",
+		Password:  "secret",
+		CreatedAt: time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC),
+		Manager:   &Account{ID: 2, Password: "secret"},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, account); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Fatalf("Was expecting writeonly attributes to be omitted, got %s", out)
+	}
+	if !strings.Contains(out.String(), `"created_at":"2016-08-17T08:27:12Z"`) ||
+		!strings.Contains(out.String(), `"email":"jane@example.com"`) ||
+		!strings.Contains(out.String(), `"manager":{"data":{"type":"accounts","id":"2"}}`) {
+		t.Fatalf("Was expecting readonly and createonly members, got %s", out)
+	}
+
+	nodeTree := bytes.NewBuffer(nil)
+	if err := marshalNodeTree(nodeTree, account); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), nodeTree.Bytes()) {
+		t.Fatalf("output differs from the Node tree:\n%s\n%s", out, nodeTree)
+	}
+}
+
+func TestAccess_unmarshal(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts     []Option
+		expected Account
+	}{
+		"default": {
+			expected: Account{
+				Name:     "Jane",
+				Email:    "jane@example.com",
+				Password: "secret",
+			},
+		},
+		"response": {
+			opts: []Option{ForResponse()},
+			expected: Account{
+				Name:      "Jane",
+				Email:     "jane@example.com",
+				Password:  "secret",
+				CreatedAt: time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC),
+				Manager:   &Account{ID: 2},
+			},
+		},
+		"create": {
+			opts: []Option{ForCreate()},
+			expected: Account{
+				Name:     "Jane",
+				Email:    "jane@example.com",
+				Password: "secret",
+			},
+		},
+		"update": {
+			opts: []Option{ForUpdate()},
+			expected: Account{
+				Name:     "Jane",
+				Password: "secret",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			account := new(Account)
+			if err := UnmarshalPayload(strings.NewReader(accountDoc), account, tc.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*account, tc.expected) {
+				t.Fatalf("Was expecting %+v, got %+v", tc.expected, *account)
+			}
+		})
+	}
+}
+
+func TestAccess_rejectReadOnly(t *testing.T) {
+	err := UnmarshalPayload(strings.NewReader(accountDoc), new(Account),
+		ForUpdate(), RejectReadOnly(), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Was expecting ErrReadOnly, got %v", err)
+	}
+
+	objs := merr.ErrorObjects()
+	var pointers []string
+	for _, obj := range objs {
+		if obj.Status != "403" {
+			t.Fatalf("Was expecting a 403 error object, got %+v", obj)
+		}
+		pointers = append(pointers, obj.Source.Pointer)
+	}
+	expected := []string{
+		"/data/attributes/email",
+		"/data/attributes/created_at",
+		"/data/relationships/manager",
+	}
+	if !reflect.DeepEqual(pointers, expected) {
+		t.Fatalf("Was expecting errors at %v, got %v", expected, pointers)
+	}
+}
+
+func TestAccess_rejectReadOnlyByDefault(t *testing.T) {
+	doc := `{"data": {"type": "accounts", "attributes": {"name": "Jane", "created_at": "2016-08-17T08:27:12Z"}}}`
+
+	err := UnmarshalPayload(strings.NewReader(doc), new(Account), RejectReadOnly())
+
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Was expecting a *FieldError wrapping ErrReadOnly, got %v", err)
+	}
+	if e, a := "/data/attributes/created_at", ferr.ErrorObject().Source.Pointer; e != a {
+		t.Fatalf("Was expecting pointer %s, got %s", e, a)
+	}
+}
+
+func TestAccess_readOnlyNotRequired(t *testing.T) {
+	type Invoice struct {
+		ID     int    `jsonapi:"primary,invoices"`
+		Number string `jsonapi:"attr,number,readonly" jsonapi-validate:"required"`
+	}
+
+	doc := `{"data": {"type": "invoices", "attributes": {}}}`
+	if err := UnmarshalPayload(strings.NewReader(doc), new(Invoice), ForCreate()); err != nil {
+		t.Fatalf("Was expecting readonly members to be skipped on create, got %v", err)
+	}
+}
+
+func TestAccess_conflictingOptions(t *testing.T) {
+	type Conflicting struct {
+		ID     int    `jsonapi:"primary,conflicting"`
+		Secret string `jsonapi:"attr,secret,readonly,writeonly"`
+	}
+
+	err := MarshalPayload(bytes.NewBuffer(nil), &Conflicting{ID: 1})
+
+	var terr *TagError
+	if !errors.As(err, &terr) || terr.Field != "Secret" {
+		t.Fatalf("Was expecting a *TagError for Secret, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrMissingPrimary is returned by CheckModel for a model without a field
+	// tagged primary.
+	ErrMissingPrimary = errors.New("model has no primary field")
+
+	// ErrDuplicateMember is returned by CheckModel for an attribute or
+	// relation whose member name is already used by another field of the
+	// model, and for a second primary or client-id field. It is wrapped in a
+	// *TagError.
+	ErrDuplicateMember = errors.New("duplicate member name")
+
+	// ErrUnsupportedType is returned by CheckModel for a primary field that
+	// is not an id, and for an attribute whose values can't be marshaled or
+	// unmarshaled, such as a channel, a function, a complex number or a map
+	// with keys that aren't strings, integers or text unmarshalers. It is
+	// wrapped in a *TagError.
+	ErrUnsupportedType = errors.New("unsupported field type")
+)
+
+// CheckModel validates the jsonapi tags of model, a struct or a struct
+// pointer, along with those of the models of its relations and of the
+// structs nested in its attributes. It reports every malformed tag, missing
+// primary field, duplicate member name, unsupported field type, unknown or
+// conflicting tag option and jsonapi-validate rule that can't be applied in a
+// MultiError, so that a model can be checked once in a unit test or at
+// startup. Tag problems are *TagError values.
+func CheckModel(model interface{}, opts ...Option) error {
+	t := reflect.TypeOf(model)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+
+	c := &modelChecker{
+		naming: newOptions(opts).naming,
+		seen:   map[reflect.Type]bool{},
+	}
+	c.check(t, true)
+
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+
+	return nil
+}
+
+// modelChecker collects the problems of the struct types reachable from a
+// model.
+type modelChecker struct {
+	naming NamingStrategy
+	seen   map[reflect.Type]bool
+	errs   MultiError
+}
+
+// check checks the tags of the struct type t, which must have a primary field
+// if it is a resource rather than a struct nested in an attribute.
+func (c *modelChecker) check(t reflect.Type, resource bool) {
+	if c.seen[t] {
+		return
+	}
+	c.seen[t] = true
+
+	var hasPrimary, hasClientID bool
+	members := map[string]bool{}
+	var related []reflect.Type
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		tag := structField.Tag.Get(annotationJSONAPI)
+		if tag == "" {
+			continue
+		}
+
+		field, err := parseFieldTag(structField, tag, c.naming)
+		if err != nil && strings.HasPrefix(tag, annotationPrimary) {
+			// A malformed primary tag is reported as such, not as a missing
+			// primary field
+			hasPrimary = true
+		}
+		if err == nil {
+			err = checkOptions(field)
+		}
+		if err == nil {
+			switch field.annotation {
+			case annotationPrimary:
+				if hasPrimary {
+					err = ErrDuplicateMember
+				} else if !isIDType(structField.Type) {
+					err = ErrUnsupportedType
+				}
+				hasPrimary = true
+			case annotationClientID:
+				if hasClientID {
+					err = ErrDuplicateMember
+				}
+				hasClientID = true
+			default:
+				if members[field.name] {
+					err = ErrDuplicateMember
+				}
+				members[field.name] = true
+			}
+		}
+		if err == nil {
+			switch {
+			case field.annotation == annotationAttribute:
+				err = c.checkAttributeType(structField.Type, map[reflect.Type]bool{})
+			case field.annotation == annotationRelation && field.linkageType == "":
+				related = append(related, relatedType(structField.Type))
+			}
+		}
+
+		if err != nil {
+			c.errs = append(c.errs, &TagError{Type: t, Field: structField.Name, Tag: tag, Err: err})
+		}
+
+		if rules, ok := structField.Tag.Lookup(annotationValidate); ok && field != nil {
+			var ruleErr error
+			switch field.annotation {
+			case annotationAttribute, annotationRelation:
+				ruleErr = checkValidationRules(rules, structField.Type)
+			default:
+				ruleErr = fmt.Errorf("%w: rules only apply to attributes and relations", ErrInvalidRule)
+			}
+			if ruleErr != nil {
+				c.errs = append(c.errs, &TagError{Type: t, Field: structField.Name, Tag: rules, Err: ruleErr})
+			}
+		}
+	}
+
+	if resource && !hasPrimary {
+		c.errs = append(c.errs, fmt.Errorf("jsonapi: %v: %w", t, ErrMissingPrimary))
+	}
+
+	for _, rt := range related {
+		c.check(rt, true)
+	}
+}
+
+// checkOptions returns an error for a tag option that doesn't apply to the
+// field, or that conflicts with an earlier option. Conflicting access options
+// are reported by parseAccess.
+func checkOptions(field *fieldInfo) error {
+	if len(field.args) < 3 {
+		return nil
+	}
+
+	var format bool
+	for _, opt := range field.args[2:] {
+		switch {
+		case field.annotation == annotationPrimary:
+			return fmt.Errorf("unknown tag option %q", opt)
+		case opt == annotationOmitEmpty, opt == annotationReadOnly,
+			opt == annotationWriteOnly, opt == annotationCreateOnly:
+		case field.annotation == annotationRelation && strings.HasPrefix(opt, annotationType+"="):
+		case field.annotation == annotationAttribute && opt == annotationKeepZone:
+		case field.annotation == annotationAttribute && isTimeFormatOption(opt):
+			if format {
+				return fmt.Errorf("conflicting tag option %q", opt)
+			}
+			format = true
+		default:
+			return fmt.Errorf("unknown tag option %q", opt)
+		}
+	}
+
+	return nil
+}
+
+// checkAttributeType returns ErrUnsupportedType if values of the attribute
+// type t can't be marshaled or unmarshaled, and checks the structs with
+// jsonapi tags that t contains.
+func (c *modelChecker) checkAttributeType(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] || isTimeType(t) || t == durationType || isMarshaler(t) ||
+		reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return nil
+	}
+	if _, ok := nullValueIndex(t); ok {
+		return nil
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return ErrUnsupportedType
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return c.checkAttributeType(t.Elem(), seen)
+	case reflect.Map:
+		if !isMapKeyType(t.Key()) {
+			return ErrUnsupportedType
+		}
+		return c.checkAttributeType(t.Elem(), seen)
+	case reflect.Struct:
+		if hasJSONAPITags(t) {
+			c.check(t, false)
+		}
+	}
+
+	return nil
+}
+
+// isMapKeyType reports whether parseMapKey can parse the keys of a map with
+// key type t.
+func isMapKeyType(t reflect.Type) bool {
+	if t.Kind() != reflect.String && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckModel_validModels(t *testing.T) {
+	for _, model := range []interface{}{
+		new(Blog), Post{}, new(Company), new(Article), new(Order), new(Note),
+		new(TimeFormats), new(Schedule), new(Nullables), new(ValidatedArticle),
+	} {
+		if err := CheckModel(model); err != nil {
+			t.Errorf("%T: unexpected error %v", model, err)
+		}
+	}
+
+	if err := CheckModel(new(NamedArticle), WithNamingStrategy(SnakeCase)); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestCheckModel_notAStruct(t *testing.T) {
+	for _, model := range []interface{}{nil, "posts", []*Post{}} {
+		if err := CheckModel(model); err != ErrUnexpectedType {
+			t.Errorf("%T: was expecting ErrUnexpectedType, got %v", model, err)
+		}
+	}
+}
+
+func TestCheckModel_missingPrimary(t *testing.T) {
+	type NoPrimary struct {
+		Name string `jsonapi:"attr,name"`
+	}
+
+	err := CheckModel(new(NoPrimary))
+	if !errors.Is(err, ErrMissingPrimary) {
+		t.Fatalf("Was expecting ErrMissingPrimary, got %v", err)
+	}
+
+	// A malformed primary tag is not also reported as missing
+	err = CheckModel(new(BadModel))
+	if !errors.Is(err, ErrBadJSONAPIStructTag) {
+		t.Fatalf("Was expecting ErrBadJSONAPIStructTag, got %v", err)
+	}
+	if errors.Is(err, ErrMissingPrimary) {
+		t.Fatalf("Was not expecting ErrMissingPrimary, got %v", err)
+	}
+}
+
+func TestCheckModel_reportsEveryProblem(t *testing.T) {
+	type Broken struct {
+		ID       string                 `jsonapi:"primary,broken"`
+		Other    string                 `jsonapi:"primary,broken"`
+		Title    string                 `jsonapi:"attr,title"`
+		Heading  string                 `jsonapi:"attr,title"`
+		Author   *Author                `jsonapi:"relation,title"`
+		Done     chan bool              `jsonapi:"attr,done"`
+		Ratio    complex128             `jsonapi:"attr,ratio"`
+		Lookup   map[interface{}]string `jsonapi:"attr,lookup"`
+		Secret   string                 `jsonapi:"attr,secret,readonly,writeonly"`
+		Created  int64                  `jsonapi:"attr,created,iso8601,rfc3339"`
+		Typo     string                 `jsonapi:"attr,typo,omitemtpy"`
+		Label    string                 `jsonapi:"label"`
+		internal string                 `jsonapi:"attr,internal"`
+	}
+
+	err := CheckModel(new(Broken))
+
+	var errs MultiError
+	if !errors.As(err, &errs) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	want := map[string]error{
+		"Other":    ErrDuplicateMember,
+		"Heading":  ErrDuplicateMember,
+		"Author":   ErrDuplicateMember,
+		"Done":     ErrUnsupportedType,
+		"Ratio":    ErrUnsupportedType,
+		"Lookup":   ErrUnsupportedType,
+		"Secret":   nil,
+		"Created":  nil,
+		"Typo":     nil,
+		"Label":    nil,
+		"internal": ErrUnexportedField,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Was expecting %d errors, got %d: %v", len(want), len(errs), err)
+	}
+	for _, e := range errs {
+		var terr *TagError
+		if !errors.As(e, &terr) {
+			t.Fatalf("Was expecting a *TagError, got %v", e)
+		}
+		sentinel, ok := want[terr.Field]
+		if !ok {
+			t.Errorf("Unexpected error for %s: %v", terr.Field, e)
+			continue
+		}
+		if sentinel != nil && !errors.Is(e, sentinel) {
+			t.Errorf("Was expecting %v for %s, got %v", sentinel, terr.Field, e)
+		}
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, `conflicting tag option "rfc3339"`) ||
+		!strings.Contains(msg, `unknown tag option "omitemtpy"`) {
+		t.Fatalf("Was expecting the option errors to be described, got %v", msg)
+	}
+}
+
+func TestCheckModel_validationRules(t *testing.T) {
+	type Rules struct {
+		ID       string   `jsonapi:"primary,rules" jsonapi-validate:"required"`
+		Title    string   `jsonapi:"attr,title" jsonapi-validate:"required,minlen=3,regex=^[A-Z]"`
+		Rating   *int     `jsonapi:"attr,rating" jsonapi-validate:"min=abc"`
+		Name     string   `jsonapi:"attr,name" jsonapi-validate:"max=5"`
+		Count    int      `jsonapi:"attr,count" jsonapi-validate:"maxlen=3"`
+		Size     string   `jsonapi:"attr,size" jsonapi-validate:"len=-1"`
+		Slug     string   `jsonapi:"attr,slug" jsonapi-validate:"regex=[a-"`
+		Code     string   `jsonapi:"attr,code" jsonapi-validate:"uppercase,isbn"`
+		Comments []*Post  `jsonapi:"relation,comments" jsonapi-validate:"maxlen=1"`
+		Author   *Comment `jsonapi:"relation,author" jsonapi-validate:"required=yes"`
+	}
+
+	err := CheckModel(new(Rules))
+
+	var errs MultiError
+	if !errors.As(err, &errs) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var fields []string
+	for _, e := range errs {
+		var terr *TagError
+		if !errors.As(e, &terr) || !errors.Is(e, ErrInvalidRule) {
+			t.Fatalf("Was expecting a *TagError wrapping ErrInvalidRule, got %v", e)
+		}
+		fields = append(fields, terr.Field)
+	}
+	if want := []string{"ID", "Rating", "Name", "Count", "Size", "Slug", "Code", "Author"}; strings.Join(fields, " ") != strings.Join(want, " ") {
+		t.Fatalf("Was expecting errors for %v, got %v", want, err)
+	}
+	if !strings.Contains(err.Error(), `invalid validation rule "isbn": unknown rule`) {
+		t.Fatalf("Was expecting the unknown rule to be described, got %v", err)
+	}
+
+	if _, err := JSONSchemas(new(Rules)); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("Was expecting Register and the generators to reject the rules, got %v", err)
+	}
+}
+
+func TestCheckModel_relatedAndNestedModels(t *testing.T) {
+	type Unidentified struct {
+		Name string `jsonapi:"attr,name"`
+	}
+	type Detail struct {
+		Callback func() `jsonapi:"attr,callback"`
+	}
+	type Parent struct {
+		ID      string        `jsonapi:"primary,parents"`
+		Child   *Unidentified `jsonapi:"relation,child"`
+		Details []Detail      `jsonapi:"attr,details"`
+		Tagged  []string      `jsonapi:"relation,tagged,type=tags"`
+	}
+
+	err := CheckModel(new(Parent))
+
+	var errs MultiError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Was expecting two errors, got %v", err)
+	}
+	var terr *TagError
+	if !errors.As(errs[0], &terr) || terr.Field != "Callback" || !errors.Is(terr, ErrUnsupportedType) {
+		t.Fatalf("Was expecting ErrUnsupportedType for the nested struct, got %v", errs[0])
+	}
+	if !errors.Is(errs[1], ErrMissingPrimary) || !strings.Contains(errs[1].Error(), "Unidentified") {
+		t.Fatalf("Was expecting ErrMissingPrimary for the related model, got %v", errs[1])
+	}
+}
+
+func TestCheckModel_unsupportedPrimary(t *testing.T) {
+	type FloatID struct {
+		ID float64 `jsonapi:"primary,floats"`
+	}
+
+	if err := CheckModel(new(FloatID)); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("Was expecting ErrUnsupportedType, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Codec encodes and decodes JSON values. Implementations must follow the
+// semantics of encoding/json's Marshal and Unmarshal, including the json
+// struct tags of the payload types, as goccy/go-json, sonic and the
+// encoding/json/v2 compatibility API do.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WithCodec makes the marshal and unmarshal functions use c instead of
+// encoding/json to encode and decode documents.
+//
+// MarshalPayload normally writes its output without encoding/json; with a
+// codec it builds the payload returned by Marshal and encodes it with c.
+func WithCodec(c Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// decode reads the JSON document from r into v.
+func (o *options) decode(r io.Reader, v interface{}) error {
+	if o.codec == nil {
+		return json.NewDecoder(r).Decode(v)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return o.codec.Unmarshal(b, v)
+}
+
+func (o *options) marshal(v interface{}) ([]byte, error) {
+	if o.codec == nil {
+		return json.Marshal(v)
+	}
+
+	return o.codec.Marshal(v)
+}
+
+func (o *options) unmarshal(data []byte, v interface{}) error {
+	if o.codec == nil {
+		return json.Unmarshal(data, v)
+	}
+
+	return o.codec.Unmarshal(data, v)
+}
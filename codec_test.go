@@ -0,0 +1,150 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// countingCodec is encoding/json counting its calls.
+type countingCodec struct {
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return json.Unmarshal(data, v)
+}
+
+func TestWithCodec_marshal(t *testing.T) {
+	codec := new(countingCodec)
+
+	for name, marshal := range map[string]func(io.Writer) error{
+		"MarshalPayload": func(w io.Writer) error {
+			return MarshalPayload(w, testBlog(), WithCodec(codec))
+		},
+		"MarshalPayloadWithoutIncluded": func(w io.Writer) error {
+			return MarshalPayloadWithoutIncluded(w, testBlog(), WithCodec(codec))
+		},
+		"MarshalOnePayloadEmbedded": func(w io.Writer) error {
+			return MarshalOnePayloadEmbedded(w, testBlog(), WithCodec(codec))
+		},
+		"MarshalErrors": func(w io.Writer) error {
+			return MarshalErrors(w, []*ErrorObject{{Title: "Bad"}}, WithCodec(codec))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			codec.marshals = 0
+
+			out := bytes.NewBuffer(nil)
+			if err := marshal(out); err != nil {
+				t.Fatal(err)
+			}
+			if codec.marshals != 1 {
+				t.Fatalf("Was expecting the codec to be used once, got %d", codec.marshals)
+			}
+			if !json.Valid(out.Bytes()) || out.Bytes()[out.Len()-1] != '\n' {
+				t.Fatalf("Was expecting a JSON document ending with a newline, got %q", out)
+			}
+		})
+	}
+}
+
+func TestWithCodec_sameOutput(t *testing.T) {
+	want := bytes.NewBuffer(nil)
+	if err := MarshalPayload(want, testBlog()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := bytes.NewBuffer(nil)
+	if err := MarshalPayload(got, testBlog(), WithCodec(new(countingCodec))); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatalf("Was expecting the same output with encoding/json as codec:\n%s\n%s", got, want)
+	}
+}
+
+func TestWithCodec_unmarshal(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, []*Blog{testBlog()}); err != nil {
+		t.Fatal(err)
+	}
+	doc := out.Bytes()
+
+	codec := new(countingCodec)
+	blogs, err := UnmarshalManyPayload(bytes.NewReader(doc), reflect.TypeOf(new(Blog)), WithCodec(codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 1 || blogs[0].(*Blog).Title != "Title 1" {
+		t.Fatalf("Unexpected blogs %v", blogs)
+	}
+	if codec.unmarshals != 1 {
+		t.Fatalf("Was expecting the codec to be used once, got %d", codec.unmarshals)
+	}
+}
+
+func TestWithCodec_streaming(t *testing.T) {
+	codec := new(countingCodec)
+
+	out := bytes.NewBuffer(nil)
+	enc := NewEncoder(out, WithCodec(codec))
+	if err := enc.WriteData(&Blog{ID: 1, Title: "Title"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if codec.marshals == 0 {
+		t.Fatal("Was expecting the Encoder to use the codec")
+	}
+
+	blog := new(Blog)
+	if err := NewDecoder(out, WithCodec(codec)).Decode(blog); err != nil {
+		t.Fatal(err)
+	}
+	if blog.Title != "Title" || codec.unmarshals != 1 {
+		t.Fatalf("Was expecting the Decoder to use the codec, got %+v and %d calls", blog, codec.unmarshals)
+	}
+}
+
+func TestRuntime_WithOptions(t *testing.T) {
+	codec := new(countingCodec)
+	runtime := NewRuntime().WithOptions(WithCodec(codec))
+
+	out := bytes.NewBuffer(nil)
+	if err := runtime.MarshalPayload(out, testBlog()); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.UnmarshalPayload(out, new(Blog)); err != nil {
+		t.Fatal(err)
+	}
+
+	if codec.marshals != 1 || codec.unmarshals != 1 {
+		t.Fatalf("Was expecting the runtime options to apply, got %d and %d calls",
+			codec.marshals, codec.unmarshals)
+	}
+}
@@ -0,0 +1,606 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Violation describes a part of a document that does not conform to the
+// JSON API specification.
+type Violation struct {
+	// Pointer is a JSON Pointer to the offending value, e.g. "/data/id".
+	Pointer string
+
+	// Detail is a human-readable explanation of the violation.
+	Detail string
+}
+
+// Error implements the `Error` interface.
+func (v *Violation) Error() string {
+	return fmt.Sprintf("jsonapi: %s: %s", v.Pointer, v.Detail)
+}
+
+// ErrorObject converts the violation into a "400 Bad Request" error object
+// pointing at the offending value.
+func (v *Violation) ErrorObject() *ErrorObject {
+	return &ErrorObject{
+		Title:  "Invalid Document",
+		Detail: v.Detail,
+		Status: "400",
+		Source: &Source{Pointer: v.Pointer},
+	}
+}
+
+var (
+	topLevelMembers = memberSet("data", "errors", "meta", "jsonapi", "links", "included")
+	resourceMembers = memberSet("type", "id", "lid", "attributes", "relationships", "links", "meta")
+
+	identifierMembers   = memberSet("type", "id", "lid", "meta")
+	relationshipMembers = memberSet("links", "data", "meta")
+	errorMembers        = memberSet("id", "links", "status", "code", "title", "detail", "source", "meta")
+	errorSourceMembers  = memberSet("pointer", "parameter", "header")
+	jsonapiMembers      = memberSet("version", "ext", "profile", "meta")
+	linkMembers         = memberSet("href", "rel", "describedby", "title", "type", "hreflang", "meta")
+
+	// reservedAttributeMembers may not appear in any object that is or is
+	// contained in an attribute value. They remain valid attribute and
+	// relationship names.
+	reservedAttributeMembers = memberSet("relationships", "links")
+)
+
+// errTrailingData is returned by Validate for data following the document.
+var errTrailingData = errors.New("jsonapi: invalid data after the top-level value")
+
+func memberSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+
+	return set
+}
+
+// Validate reads a JSON API document from in and checks it against the
+// document structure rules of the specification: top-level members and the
+// exclusivity of "data" and "errors", the shape of resource objects,
+// relationships, links and error objects, member names and reserved names,
+// the uniqueness of type and id pairs and the full linkage of "included"
+// resources.
+//
+// The returned violations are in a stable order, independent of the order of
+// members in the document: the top-level object and its "meta", "links",
+// "jsonapi" and "errors" members come first, then the primary data and the
+// included resources, and the members of each object are checked sorted by
+// name. The error is only non-nil when `in` could not be read or is not a
+// single valid JSON value.
+func Validate(in io.Reader) ([]*Violation, error) {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	// Only white space may follow the document
+	var extra json.RawMessage
+	switch err := dec.Decode(&extra); err {
+	case io.EOF:
+	case nil:
+		return nil, errTrailingData
+	default:
+		return nil, err
+	}
+
+	v := &documentValidator{resources: map[string]string{}}
+	v.validateDocument(doc)
+
+	return v.violations, nil
+}
+
+// documentValidator accumulates the violations found in a single document.
+type documentValidator struct {
+	violations []*Violation
+
+	// resources maps the "type,id" key of every resource object to the JSON
+	// Pointer of its first occurrence.
+	resources map[string]string
+}
+
+func (v *documentValidator) report(pointer, format string, args ...interface{}) {
+	v.violations = append(v.violations, &Violation{
+		Pointer: pointer,
+		Detail:  fmt.Sprintf(format, args...),
+	})
+}
+
+// object asserts that value is a JSON object and reports a violation
+// otherwise.
+func (v *documentValidator) object(value interface{}, pointer, what string) (map[string]interface{}, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		v.report(pointer, "%s must be an object", what)
+	}
+
+	return obj, ok
+}
+
+// members reports every member of obj that is not in allowed, visiting them
+// in a stable order.
+func (v *documentValidator) members(obj map[string]interface{}, pointer, what string, allowed map[string]bool) {
+	for _, name := range sortedKeys(obj) {
+		if !allowed[name] && !isAtMember(name) {
+			v.report(pointerTo(pointer, name), "%q is not a valid member of %s", name, what)
+		}
+	}
+}
+
+func (v *documentValidator) validateDocument(doc interface{}) {
+	top, ok := v.object(doc, "", "a document")
+	if !ok {
+		return
+	}
+
+	v.members(top, "", "the top-level object", topLevelMembers)
+
+	data, hasData := top["data"]
+	_, hasErrors := top["errors"]
+	_, hasMeta := top["meta"]
+
+	if !hasData && !hasErrors && !hasMeta {
+		v.report("", `a document must contain at least one of "data", "errors" or "meta"`)
+	}
+	if hasData && hasErrors {
+		v.report("", `"data" and "errors" must not coexist in the same document`)
+	}
+	if _, ok := top["included"]; ok && !hasData {
+		v.report("/included", `"included" must not be present without "data"`)
+	}
+
+	if meta, ok := top["meta"]; ok {
+		v.validateMeta(meta, "/meta")
+	}
+	if links, ok := top["links"]; ok {
+		v.validateLinks(links, "/links")
+	}
+	if jsonapi, ok := top["jsonapi"]; ok {
+		v.validateJSONAPIObject(jsonapi, "/jsonapi")
+	}
+	if errs, ok := top["errors"]; ok {
+		v.validateErrors(errs, "/errors")
+	}
+
+	// linked holds the keys of the resources that are reachable from the
+	// primary data, and edges the linkage of every resource object.
+	linked := map[string]bool{}
+	edges := map[string][]string{}
+
+	if hasData {
+		switch d := data.(type) {
+		case nil:
+		case []interface{}:
+			for i, r := range d {
+				key, links := v.validateResource(r, fmt.Sprintf("/data/%d", i), true)
+				v.link(key, links, linked, edges)
+			}
+		default:
+			key, links := v.validateResource(d, "/data", true)
+			v.link(key, links, linked, edges)
+		}
+	}
+
+	included, ok := top["included"]
+	if !ok {
+		return
+	}
+	list, ok := included.([]interface{})
+	if !ok {
+		v.report("/included", `"included" must be an array`)
+		return
+	}
+
+	keys := make([]string, len(list))
+	for i, r := range list {
+		key, links := v.validateResource(r, fmt.Sprintf("/included/%d", i), false)
+		keys[i] = key
+		if key != "" {
+			edges[key] = append(edges[key], links...)
+		}
+	}
+
+	// Every included resource must be reachable from the primary data,
+	// either directly or through other included resources.
+	queue := make([]string, 0, len(linked))
+	for key := range linked {
+		queue = append(queue, key)
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[key] {
+			if !linked[next] {
+				linked[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for i, key := range keys {
+		if key != "" && !linked[key] {
+			v.report(fmt.Sprintf("/included/%d", i),
+				"included resource is not linked from the primary data or another included resource")
+		}
+	}
+}
+
+// link records that the primary resource key links to links.
+func (v *documentValidator) link(key string, links []string, linked map[string]bool, edges map[string][]string) {
+	for _, l := range links {
+		linked[l] = true
+	}
+	if key != "" {
+		edges[key] = append(edges[key], links...)
+	}
+}
+
+// validateResource checks a resource object and returns its "type,id" key,
+// or "" if it has none, together with the keys of the resources it links to.
+// The id may only be omitted by primary data, which may be a resource that
+// is yet to be created.
+func (v *documentValidator) validateResource(value interface{}, pointer string, primary bool) (string, []string) {
+	obj, ok := v.object(value, pointer, "a resource object")
+	if !ok {
+		return "", nil
+	}
+
+	v.members(obj, pointer, "a resource object", resourceMembers)
+
+	key := v.validateIdentity(obj, pointer, primary)
+	if key != "" {
+		if first, ok := v.resources[key]; ok {
+			v.report(pointer, "duplicate resource object, %s has the same type and id", first)
+		} else {
+			v.resources[key] = pointer
+		}
+	}
+
+	fields := map[string]bool{}
+
+	if attrs, ok := obj["attributes"]; ok {
+		if attributes, ok := v.object(attrs, pointer+"/attributes", `"attributes"`); ok {
+			for _, name := range sortedKeys(attributes) {
+				p := pointerTo(pointer+"/attributes", name)
+				v.validateFieldName(name, p)
+				fields[name] = true
+				v.validateAttributeValue(attributes[name], p)
+			}
+		}
+	}
+
+	var links []string
+	if rels, ok := obj["relationships"]; ok {
+		if relationships, ok := v.object(rels, pointer+"/relationships", `"relationships"`); ok {
+			for _, name := range sortedKeys(relationships) {
+				p := pointerTo(pointer+"/relationships", name)
+				v.validateFieldName(name, p)
+				if fields[name] {
+					v.report(p, "%q is used as both an attribute and a relationship", name)
+				}
+				links = append(links, v.validateRelationship(relationships[name], p)...)
+			}
+		}
+	}
+
+	if l, ok := obj["links"]; ok {
+		v.validateLinks(l, pointer+"/links")
+	}
+	if meta, ok := obj["meta"]; ok {
+		v.validateMeta(meta, pointer+"/meta")
+	}
+
+	return key, links
+}
+
+// validateIdentity checks the "type", "id" and "lid" members of a resource
+// object or resource identifier object and returns its key.
+func (v *documentValidator) validateIdentity(obj map[string]interface{}, pointer string, idOptional bool) string {
+	typ, ok := obj["type"].(string)
+	if !ok {
+		v.report(pointer+"/type", `"type" must be a string`)
+	} else if typ == "" {
+		v.report(pointer+"/type", `"type" must not be empty`)
+	} else if !isValidMemberName(typ) {
+		v.report(pointer+"/type", "%q is not a valid member name", typ)
+	}
+
+	var key string
+	if id, present := obj["id"]; present {
+		s, ok := id.(string)
+		if !ok {
+			v.report(pointer+"/id", `"id" must be a string`)
+		} else {
+			key = typ + "," + s
+		}
+	} else if lid, present := obj["lid"]; present {
+		s, ok := lid.(string)
+		if !ok {
+			v.report(pointer+"/lid", `"lid" must be a string`)
+		} else {
+			key = typ + ",lid:" + s
+		}
+	} else if !idOptional {
+		v.report(pointer, `"id" is required`)
+	}
+
+	if typ == "" {
+		return ""
+	}
+
+	return key
+}
+
+func (v *documentValidator) validateFieldName(name, pointer string) {
+	if isAtMember(name) {
+		return
+	}
+
+	if name == "type" || name == "id" {
+		v.report(pointer, "%q can't be used as a field name, it is shared with the resource identity", name)
+	} else if !isValidMemberName(name) {
+		v.report(pointer, "%q is not a valid member name", name)
+	}
+}
+
+// validateAttributeValue checks the member names of the objects within an
+// attribute value and that none of them use a reserved member name.
+func (v *documentValidator) validateAttributeValue(value interface{}, pointer string) {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		for _, name := range sortedKeys(val) {
+			p := pointerTo(pointer, name)
+			if reservedAttributeMembers[name] {
+				v.report(p, "%q is reserved and must not be used within attribute values", name)
+			} else if !isValidMemberName(name) {
+				v.report(p, "%q is not a valid member name", name)
+			}
+			v.validateAttributeValue(val[name], p)
+		}
+	case []interface{}:
+		for i, item := range val {
+			v.validateAttributeValue(item, fmt.Sprintf("%s/%d", pointer, i))
+		}
+	}
+}
+
+// validateRelationship checks a relationship object and returns the keys of
+// the resources its linkage refers to.
+func (v *documentValidator) validateRelationship(value interface{}, pointer string) []string {
+	obj, ok := v.object(value, pointer, "a relationship")
+	if !ok {
+		return nil
+	}
+
+	v.members(obj, pointer, "a relationship object", relationshipMembers)
+
+	_, hasLinks := obj["links"]
+	data, hasData := obj["data"]
+	_, hasMeta := obj["meta"]
+	if !hasLinks && !hasData && !hasMeta {
+		v.report(pointer, `a relationship must contain at least one of "links", "data" or "meta"`)
+	}
+
+	if l, ok := obj["links"]; ok {
+		v.validateLinks(l, pointer+"/links")
+	}
+	if meta, ok := obj["meta"]; ok {
+		v.validateMeta(meta, pointer+"/meta")
+	}
+
+	var keys []string
+	switch d := data.(type) {
+	case nil:
+	case []interface{}:
+		for i, r := range d {
+			if key := v.validateIdentifier(r, fmt.Sprintf("%s/data/%d", pointer, i)); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	default:
+		if key := v.validateIdentifier(d, pointer+"/data"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (v *documentValidator) validateIdentifier(value interface{}, pointer string) string {
+	obj, ok := v.object(value, pointer, "resource linkage")
+	if !ok {
+		return ""
+	}
+
+	v.members(obj, pointer, "a resource identifier object", identifierMembers)
+	if meta, ok := obj["meta"]; ok {
+		v.validateMeta(meta, pointer+"/meta")
+	}
+
+	return v.validateIdentity(obj, pointer, false)
+}
+
+func (v *documentValidator) validateLinks(value interface{}, pointer string) {
+	obj, ok := v.object(value, pointer, `"links"`)
+	if !ok {
+		return
+	}
+
+	for _, name := range sortedKeys(obj) {
+		p := pointerTo(pointer, name)
+		switch link := obj[name].(type) {
+		case nil, string:
+		case map[string]interface{}:
+			v.members(link, p, "a link object", linkMembers)
+			if _, ok := link["href"].(string); !ok {
+				v.report(p+"/href", `a link object must contain an "href" string`)
+			}
+			if meta, ok := link["meta"]; ok {
+				v.validateMeta(meta, p+"/meta")
+			}
+		default:
+			v.report(p, "a link must be a string, a link object or null")
+		}
+	}
+}
+
+func (v *documentValidator) validateMeta(value interface{}, pointer string) {
+	v.object(value, pointer, `"meta"`)
+}
+
+func (v *documentValidator) validateJSONAPIObject(value interface{}, pointer string) {
+	obj, ok := v.object(value, pointer, `"jsonapi"`)
+	if !ok {
+		return
+	}
+
+	v.members(obj, pointer, "the jsonapi object", jsonapiMembers)
+	if version, ok := obj["version"]; ok {
+		if _, ok := version.(string); !ok {
+			v.report(pointer+"/version", `"version" must be a string`)
+		}
+	}
+	for _, name := range []string{"ext", "profile"} {
+		value, ok := obj[name]
+		if !ok {
+			continue
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			v.report(pointer+"/"+name, "%q must be an array of strings", name)
+			continue
+		}
+		for i, item := range list {
+			if _, ok := item.(string); !ok {
+				v.report(fmt.Sprintf("%s/%s/%d", pointer, name, i), "%q must be an array of strings", name)
+			}
+		}
+	}
+	if meta, ok := obj["meta"]; ok {
+		v.validateMeta(meta, pointer+"/meta")
+	}
+}
+
+func (v *documentValidator) validateErrors(value interface{}, pointer string) {
+	list, ok := value.([]interface{})
+	if !ok {
+		v.report(pointer, `"errors" must be an array`)
+		return
+	}
+
+	for i, e := range list {
+		p := fmt.Sprintf("%s/%d", pointer, i)
+		obj, ok := v.object(e, p, "an error object")
+		if !ok {
+			continue
+		}
+
+		v.members(obj, p, "an error object", errorMembers)
+		for _, name := range []string{"id", "status", "code", "title", "detail"} {
+			if member, ok := obj[name]; ok {
+				if _, ok := member.(string); !ok {
+					v.report(p+"/"+name, "%q must be a string", name)
+				}
+			}
+		}
+		if l, ok := obj["links"]; ok {
+			v.validateLinks(l, p+"/links")
+		}
+		if src, ok := obj["source"]; ok {
+			if source, ok := v.object(src, p+"/source", `"source"`); ok {
+				v.members(source, p+"/source", "an error source object", errorSourceMembers)
+				for _, name := range sortedKeys(source) {
+					if _, ok := source[name].(string); !ok && errorSourceMembers[name] {
+						v.report(pointerTo(p+"/source", name), "%q must be a string", name)
+					}
+				}
+			}
+		}
+		if meta, ok := obj["meta"]; ok {
+			v.validateMeta(meta, p+"/meta")
+		}
+	}
+}
+
+// isValidMemberName reports whether name conforms to the member name rules of
+// the specification: it is non-empty, consists of a-z, A-Z, 0-9, U+0080 and
+// above, hyphen-minus, low line and space, and starts and ends with one of
+// the globally allowed characters (i.e. not "-", "_" or " ").
+func isValidMemberName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case isGloballyAllowedRune(r):
+		case r == '-' || r == '_' || r == ' ':
+			if i == 0 || i == len(runes)-1 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isGloballyAllowedRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') ||
+		(r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9') ||
+		(r >= 0x80 && r != 0xFFFF)
+}
+
+// isAtMember reports whether name is an @-member, which implementations must
+// ignore wherever it appears.
+func isAtMember(name string) bool {
+	return strings.HasPrefix(name, "@") && isValidMemberName(name[1:])
+}
+
+// pointerTo appends the reference token name to the JSON Pointer pointer,
+// escaping it as described in RFC 6901.
+func pointerTo(pointer, name string) string {
+	name = strings.ReplaceAll(name, "~", "~0")
+	name = strings.ReplaceAll(name, "/", "~1")
+
+	return pointer + "/" + name
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
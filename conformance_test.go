@@ -0,0 +1,203 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate_marshaledPayloads(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		model interface{}
+	}{
+		{desc: "one", model: testBlog()},
+		{desc: "many", model: []*Blog{testBlog()}},
+		{desc: "empty_many", model: []*Blog{}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			if err := MarshalPayload(out, tc.model); err != nil {
+				t.Fatal(err)
+			}
+
+			violations, err := Validate(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(violations) != 0 {
+				t.Fatalf("Was expecting no violations, got %v", violations)
+			}
+		})
+	}
+}
+
+func TestValidate_violations(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		doc      string
+		pointers []string
+	}{
+		{
+			desc:     "not_an_object",
+			doc:      `[]`,
+			pointers: []string{""},
+		},
+		{
+			desc:     "no_primary_member",
+			doc:      `{"links":{"self":"/a"}}`,
+			pointers: []string{""},
+		},
+		{
+			desc:     "data_and_errors",
+			doc:      `{"data":null,"errors":[]}`,
+			pointers: []string{""},
+		},
+		{
+			desc:     "included_without_data",
+			doc:      `{"meta":{},"included":[]}`,
+			pointers: []string{"/included"},
+		},
+		{
+			desc:     "unknown_top_level_member",
+			doc:      `{"data":null,"foo":1,"@ext":1}`,
+			pointers: []string{"/foo"},
+		},
+		{
+			desc:     "resource_shape",
+			doc:      `{"data":{"type":1,"id":2,"extra":true}}`,
+			pointers: []string{"/data/extra", "/data/type", "/data/id"},
+		},
+		{
+			desc:     "missing_id_in_included",
+			doc:      `{"data":{"type":"a","relationships":{"b":{"data":{"type":"b","id":"1"}}}},"included":[{"type":"b"}]}`,
+			pointers: []string{"/included/0"},
+		},
+		{
+			desc: "member_names",
+			doc: `{"data":{"type":"a","id":"1","attributes":{"-bad":1,"good name":2,"type":3,"links":4,` +
+				`"nested":{"relationships":{},"ok":[{"b@d":1}]}}}}`,
+			pointers: []string{
+				"/data/attributes/-bad",
+				"/data/attributes/nested/ok/0/b@d",
+				"/data/attributes/nested/relationships",
+				"/data/attributes/type",
+			},
+		},
+		{
+			desc:     "attribute_and_relationship_clash",
+			doc:      `{"data":{"type":"a","id":"1","attributes":{"b":1},"relationships":{"b":{"data":null}}}}`,
+			pointers: []string{"/data/relationships/b"},
+		},
+		{
+			desc:     "empty_relationship",
+			doc:      `{"data":{"type":"a","id":"1","relationships":{"b":{},"c":{"data":[{"type":"c","id":"1","attributes":{}}]}}}}`,
+			pointers: []string{"/data/relationships/b", "/data/relationships/c/data/0/attributes"},
+		},
+		{
+			desc: "duplicate_resources",
+			doc: `{"data":{"type":"a","id":"1","relationships":{"b":{"data":{"type":"b","id":"1"}}}},` +
+				`"included":[{"type":"b","id":"1"},{"type":"b","id":"1"},{"type":"a","id":"1"}]}`,
+			pointers: []string{"/included/1", "/included/2", "/included/2"},
+		},
+		{
+			desc: "full_linkage",
+			doc: `{"data":[{"type":"a","id":"1","relationships":{"b":{"data":{"type":"b","id":"1"}}}}],` +
+				`"included":[{"type":"b","id":"1","relationships":{"c":{"data":[{"type":"c","id":"1"}]}}},` +
+				`{"type":"c","id":"1"},{"type":"d","id":"1"}]}`,
+			pointers: []string{"/included/2"},
+		},
+		{
+			desc:     "links",
+			doc:      `{"data":null,"links":{"self":1,"next":{"meta":{}},"prev":null}}`,
+			pointers: []string{"/links/next/href", "/links/self"},
+		},
+		{
+			desc:     "errors",
+			doc:      `{"errors":[{"status":400,"source":{"pointer":1,"foo":"bar"}},"oops"]}`,
+			pointers: []string{"/errors/0/status", "/errors/0/source/foo", "/errors/0/source/pointer", "/errors/1"},
+		},
+		{
+			desc:     "jsonapi_object",
+			doc:      `{"meta":{},"jsonapi":{"version":1.1,"ext":["a",1]}}`,
+			pointers: []string{"/jsonapi/version", "/jsonapi/ext/1"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			violations, err := Validate(strings.NewReader(tc.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pointers := []string{}
+			for _, v := range violations {
+				pointers = append(pointers, v.Pointer)
+			}
+			if !reflect.DeepEqual(tc.pointers, pointers) {
+				t.Fatalf("Was expecting violations at %q, got %v", tc.pointers, violations)
+			}
+		})
+	}
+}
+
+func TestValidate_reservedNamesAsFields(t *testing.T) {
+	doc := `{"data":{"type":"a","id":"1","attributes":{"links":"/a"},` +
+		`"relationships":{"relationships":{"data":null}}}}`
+
+	violations, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("Was expecting no violations, got %v", violations)
+	}
+}
+
+func TestValidate_invalidJSON(t *testing.T) {
+	for _, doc := range []string{`{"data":`, `{"data":null}}`, `{"data":null} {"data":null}`, `{"data":null} x`} {
+		if _, err := Validate(strings.NewReader(doc)); err == nil {
+			t.Fatalf("Was expecting an error for %s", doc)
+		}
+	}
+
+	if _, err := Validate(strings.NewReader("{\"data\":null}\n\t ")); err != nil {
+		t.Fatalf("Was expecting trailing white space to be accepted, got %v", err)
+	}
+}
+
+func TestIsValidMemberName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"title":      true,
+		"created_at": true,
+		"view-count": true,
+		"two words":  true,
+		"CamelCase":  true,
+		"ünïcode":    true,
+		"":           false,
+		"_private":   false,
+		"trailing-":  false,
+		" padded":    false,
+		"a.b":        false,
+		"a/b":        false,
+		"a+b":        false,
+	} {
+		if got := isValidMemberName(name); got != valid {
+			t.Errorf("isValidMemberName(%q) = %t, was expecting %t", name, got, valid)
+		}
+	}
+}
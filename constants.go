@@ -24,9 +24,30 @@ const (
 	annotationOmitEmpty = "omitempty"
 	annotationISO8601   = "iso8601"
 	annotationRFC3339   = "rfc3339"
+
+	annotationRFC3339Nano = "rfc3339nano"
+	annotationUnixMilli   = "unixmilli"
+	annotationUnixMicro   = "unixmicro"
+	annotationDate        = "date"
+	annotationSeconds     = "seconds"
+	annotationKeepZone    = "keepzone"
+
+	annotationReadOnly   = "readonly"
+	annotationWriteOnly  = "writeonly"
+	annotationCreateOnly = "createonly"
+
+	// annotationType is the option giving the resource type of an
+	// identifier-only relation, e.g. "type=people".
+	annotationType = "type"
+
 	annotationSeperator = ","
 
+	// annotationValidate is the companion struct tag holding the validation
+	// rules of an attribute or relation.
+	annotationValidate = "jsonapi-validate"
+
 	iso8601TimeFormat = "2006-01-02T15:04:05Z"
+	dateTimeFormat    = "2006-01-02"
 
 	// MediaType is the identifier for the JSON API media type
 	//
@@ -0,0 +1,295 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+type decoderState int
+
+const (
+	decoderStart decoderState = iota
+	decoderArray
+	decoderObject
+	decoderDone
+)
+
+// Decoder reads the primary data of a JSON API document from an input stream
+// one resource at a time.
+//
+// UnmarshalManyPayload decodes the whole document before it builds any
+// model. A Decoder instead reads the "data" array token by token, so only the
+// resource object being decoded is held in memory:
+//
+//	dec := jsonapi.NewDecoder(r.Body)
+//	for {
+//		blog := new(Blog)
+//		err := dec.Decode(blog)
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		// ...import blog...
+//	}
+//
+// Relations are resolved against the "included" resources that precede
+// "data" in the document, which the Decoder keeps in memory. Resources
+// included after "data" are only read once every model was decoded and are
+// skipped; the relations referring to them are populated from their resource
+// identifier, leaving all but the primary field zero, as UnmarshalPayload does
+// for relations that are not included. Producers that want their relations
+// resolved should therefore write "included" before "data".
+//
+// The document is always tokenized with encoding/json; a codec set with
+// WithCodec decodes the resource objects.
+type Decoder struct {
+	dec *json.Decoder
+	ctx *unmarshalContext
+
+	state decoderState
+	index int
+	data  *Node
+	err   error
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return &Decoder{
+		dec: json.NewDecoder(r),
+		ctx: newUnmarshalContext(nil, newOptions(opts)),
+	}
+}
+
+// Decode populates model, a pointer to a struct, from the next resource
+// object of the primary data. A document whose "data" is a single resource
+// object yields that resource once. Decode returns io.EOF once every resource
+// was decoded.
+//
+// An error in a resource object leaves the Decoder positioned at the next
+// one, so decoding can continue; an error in the document itself, such as
+// malformed JSON, is returned by every subsequent call.
+func (d *Decoder) Decode(model interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.state == decoderStart {
+		if err := d.seekData(); err != nil {
+			return d.fail(err)
+		}
+	}
+
+	var node *Node
+	switch d.state {
+	case decoderArray:
+		if !d.dec.More() {
+			if err := d.finish(); err != nil {
+				return d.fail(err)
+			}
+			return io.EOF
+		}
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err != nil {
+			return d.fail(err)
+		}
+		node = new(Node)
+		if err := d.ctx.opts.unmarshal(raw, node); err != nil {
+			return d.fail(err)
+		}
+	case decoderObject:
+		node = d.data
+		d.data = nil
+		if err := d.skipMembers(); err != nil {
+			return d.fail(err)
+		}
+		d.state = decoderDone
+	default:
+		return io.EOF
+	}
+
+	pointer := "/data"
+	if d.index >= 0 {
+		pointer = fmt.Sprintf("/data/%d", d.index)
+		d.index++
+	}
+
+	return d.ctx.unmarshalPrimary(node, reflect.ValueOf(model), pointer)
+}
+
+// seekData reads the top-level members up to the primary data, keeping the
+// "included" resources found on the way.
+func (d *Decoder) seekData() error {
+	if err := d.expectDelim('{'); err != nil {
+		return err
+	}
+
+	for d.dec.More() {
+		key, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "data":
+			return d.startData()
+		case "included":
+			var raw json.RawMessage
+			if err := d.dec.Decode(&raw); err != nil {
+				return err
+			}
+			var included []*Node
+			if err := d.ctx.opts.unmarshal(raw, &included); err != nil {
+				return err
+			}
+			d.ctx.addIncluded(included)
+		default:
+			if err := d.ctx.opts.checkTopLevelMember(fmt.Sprint(key)); err != nil {
+				return err
+			}
+			if err := d.skipValue(); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.state = decoderDone
+	return d.expectDelim('}')
+}
+
+func (d *Decoder) startData() error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		d.state = decoderArray
+		return nil
+	case json.Delim('{'):
+		d.state = decoderObject
+		d.index = -1
+		return d.decodeObjectMembers()
+	case nil:
+		d.state = decoderDone
+		return d.skipMembers()
+	default:
+		return fmt.Errorf("jsonapi: unexpected primary data %v", tok)
+	}
+}
+
+// decodeObjectMembers decodes the rest of a resource object whose opening
+// brace was already read.
+func (d *Decoder) decodeObjectMembers() error {
+	members := map[string]json.RawMessage{}
+	for d.dec.More() {
+		key, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := d.dec.Decode(&value); err != nil {
+			return err
+		}
+		members[fmt.Sprint(key)] = value
+	}
+	if err := d.expectDelim('}'); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	d.data = new(Node)
+
+	return d.ctx.opts.unmarshal(b, d.data)
+}
+
+// finish reads past the end of the "data" array and the remaining top-level
+// members.
+func (d *Decoder) finish() error {
+	d.state = decoderDone
+	if err := d.expectDelim(']'); err != nil {
+		return err
+	}
+
+	return d.skipMembers()
+}
+
+// skipMembers skips the remaining top-level members and reads the end of the
+// document.
+func (d *Decoder) skipMembers() error {
+	for d.dec.More() {
+		key, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := d.ctx.opts.checkTopLevelMember(fmt.Sprint(key)); err != nil {
+			return err
+		}
+		if err := d.skipValue(); err != nil {
+			return err
+		}
+	}
+
+	return d.expectDelim('}')
+}
+
+// skipValue skips the next value without holding it in memory.
+func (d *Decoder) skipValue() error {
+	depth := 0
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (d *Decoder) expectDelim(delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err == io.EOF && delim != '{' {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("jsonapi: expected %q, found %v", delim, tok)
+	}
+
+	return nil
+}
+
+func (d *Decoder) fail(err error) error {
+	d.err = err
+	return err
+}
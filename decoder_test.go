@@ -0,0 +1,168 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeAll(t *testing.T, dec *Decoder) []*Post {
+	t.Helper()
+
+	var posts []*Post
+	for {
+		post := new(Post)
+		err := dec.Decode(post)
+		if err == io.EOF {
+			return posts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+}
+
+func TestDecoder_matchesUnmarshalManyPayload(t *testing.T) {
+	second := testBlog()
+	second.ID = 6
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithoutIncluded(out, []interface{}{testBlog(), second}); err != nil {
+		t.Fatal(err)
+	}
+	doc := out.Bytes()
+
+	expected, err := UnmarshalManyPayload(bytes.NewReader(doc), reflect.TypeOf(new(Blog)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(doc))
+	for i, want := range expected {
+		blog := new(Blog)
+		if err := dec.Decode(blog); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(blog, want) {
+			t.Fatalf("resource %d: expected %+v, got %+v", i, want, blog)
+		}
+	}
+	if err := dec.Decode(new(Blog)); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoder_includedBeforeData(t *testing.T) {
+	doc := `{
+		"meta": {"total": 2},
+		"included": [{"type": "comments", "id": "1", "attributes": {"body": "foo"}}],
+		"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "A"},
+			 "relationships": {"latest_comment": {"data": {"type": "comments", "id": "1"}}}},
+			{"type": "posts", "id": "2", "attributes": {"title": "B"}}
+		]
+	}`
+
+	posts := decodeAll(t, NewDecoder(strings.NewReader(doc)))
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posts))
+	}
+	if posts[0].LatestComment == nil || posts[0].LatestComment.Body != "foo" {
+		t.Fatalf("expected the included comment to be resolved, got %+v", posts[0].LatestComment)
+	}
+	if posts[1].Title != "B" {
+		t.Fatalf("expected title B, got %q", posts[1].Title)
+	}
+}
+
+func TestDecoder_includedAfterData(t *testing.T) {
+	doc := `{
+		"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "A"},
+			 "relationships": {"latest_comment": {"data": {"type": "comments", "id": "1"}}}}
+		],
+		"included": [{"type": "comments", "id": "1", "attributes": {"body": "foo"}}]
+	}`
+
+	posts := decodeAll(t, NewDecoder(strings.NewReader(doc)))
+	if len(posts) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(posts))
+	}
+	comment := posts[0].LatestComment
+	if comment == nil || comment.ID != 1 || comment.Body != "" {
+		t.Fatalf("expected a comment holding only its identifier, got %+v", comment)
+	}
+}
+
+func TestDecoder_singleResource(t *testing.T) {
+	doc := `{"data": {"type": "posts", "id": "1", "attributes": {"title": "A"}}, "links": {"self": "/posts/1"}}`
+
+	posts := decodeAll(t, NewDecoder(strings.NewReader(doc)))
+	if len(posts) != 1 || posts[0].ID != 1 || posts[0].Title != "A" {
+		t.Fatalf("unexpected posts %+v", posts)
+	}
+}
+
+func TestDecoder_emptyData(t *testing.T) {
+	for _, doc := range []string{`{"data": null}`, `{"data": []}`, `{"meta": {"total": 0}}`} {
+		if posts := decodeAll(t, NewDecoder(strings.NewReader(doc))); len(posts) != 0 {
+			t.Fatalf("%s: expected no posts, got %d", doc, len(posts))
+		}
+	}
+}
+
+func TestDecoder_continuesAfterResourceError(t *testing.T) {
+	doc := `{"data": [
+		{"type": "posts", "id": "1", "attributes": {"title": 1}},
+		{"type": "posts", "id": "2", "attributes": {"title": "B"}}
+	]}`
+
+	dec := NewDecoder(strings.NewReader(doc), CollectErrors())
+	err := dec.Decode(new(Post))
+	merr, ok := err.(MultiError)
+	if !ok || len(merr) != 1 {
+		t.Fatalf("expected a MultiError with one entry, got %v", err)
+	}
+	if ferr := merr[0].(*FieldError); ferr.Pointer != "/data/0/attributes/title" {
+		t.Fatalf("unexpected pointer %q", ferr.Pointer)
+	}
+
+	post := new(Post)
+	if err := dec.Decode(post); err != nil {
+		t.Fatal(err)
+	}
+	if post.ID != 2 || post.Title != "B" {
+		t.Fatalf("unexpected post %+v", post)
+	}
+}
+
+func TestDecoder_malformedDocument(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`{"data": [{"type": "posts", "id": "1"}, {`))
+	if err := dec.Decode(new(Post)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := dec.Decode(new(Post))
+	if err == nil || err == io.EOF {
+		t.Fatalf("expected a syntax error, got %v", err)
+	}
+	if again := dec.Decode(new(Post)); again != err {
+		t.Fatalf("expected the error to be returned again, got %v", again)
+	}
+}
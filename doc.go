@@ -69,6 +69,13 @@ The following extra arguments are also supported:
 
 "omitempty": excludes the fields value from the "attribute" hash.
 "iso8601": uses the ISO8601 timestamp format when serialising or deserialising the time.Time value.
+"rfc3339", "rfc3339nano", "date", "unixmilli", "unixmicro": use the RFC3339 timestamp format,
+with or without nanoseconds, the "2006-01-02" date format or unix timestamps in milliseconds or
+microseconds instead of the default unix timestamp in seconds. Layouts given to RegisterTimeFormat
+may be selected by their name.
+"seconds": encodes time.Duration values as a number of seconds instead of nanoseconds; with
+"iso8601" they are ISO8601 duration strings.
+"keepzone": keeps the offset of the time.Time value in string formats instead of converting it to UTC.
 
 Value, relation: "relation,<key name in relationships hash>"
 
@@ -76,6 +83,15 @@ Relations are struct fields that represent a one-to-one or one-to-many to other
 jsonapi will traverse the graph of relationships and marshal or unmarshal records.  The first
 argument must be, "relation", and the second should be the name of the relationship, used as
 the key in the "relationships" hash for the record.
+The "type=<resource type>" extra argument makes an id field, or a slice of ids, an
+identifier-only relation, marshaled as resource linkage without included resources.
+
+Tags are parsed when a model is first used. CheckModel validates the tags of a model and its
+related models up front, and Register does the same at startup, panicking on a broken model.
+Registered models are recorded in DefaultRegistry, which looks up models by resource type and
+lets UnmarshalAny decode documents whose type isn't known in advance. JSONSchemas generates
+the JSON Schemas of the request and response documents of a model from its tags.
+OpenAPI generates an OpenAPI 3.1 document describing the endpoints of the registered models.
 
 Use the methods below to Marshal and Unmarshal jsonapi.org json payloads.
 
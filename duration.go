@@ -0,0 +1,199 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatDuration returns the attribute value of d: an ISO 8601 duration
+// string with the iso8601 option, or a number of seconds or nanoseconds.
+func (f timeFormat) formatDuration(d time.Duration) interface{} {
+	switch {
+	case f.layout == iso8601TimeFormat:
+		return formatISODuration(d)
+	case f.durationUnit == time.Second:
+		return d.Seconds()
+	}
+
+	return int64(d)
+}
+
+// parseDuration returns the duration of the attribute value v.
+func (f timeFormat) parseDuration(v reflect.Value) (time.Duration, error) {
+	if f.layout == iso8601TimeFormat {
+		if v.Kind() != reflect.String {
+			return 0, ErrInvalidDuration
+		}
+
+		return parseISODuration(v.String())
+	}
+
+	switch v.Kind() {
+	case reflect.Float64:
+		if f.durationUnit == time.Second {
+			return time.Duration(math.Round(v.Float() * float64(time.Second))), nil
+		}
+		return time.Duration(v.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return time.Duration(v.Int()) * f.durationUnit, nil
+	}
+
+	return 0, ErrInvalidDuration
+}
+
+func handleDuration(attribute interface{}, args []string, fieldValue reflect.Value) (reflect.Value, error) {
+	d, err := parseTimeFormat(args).parseDuration(reflect.ValueOf(attribute))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if fieldValue.Kind() == reflect.Ptr {
+		return reflect.ValueOf(&d), nil
+	}
+
+	return reflect.ValueOf(d), nil
+}
+
+// formatISODuration formats d as an ISO 8601 duration in hours, minutes and
+// seconds, e.g. "PT1H30M" or "-PT0.5S".
+func formatISODuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	b := make([]byte, 0, 24)
+	u := uint64(d)
+	if d < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	b = append(b, 'P', 'T')
+
+	if h := u / uint64(time.Hour); h > 0 {
+		b = append(strconv.AppendUint(b, h, 10), 'H')
+		u -= h * uint64(time.Hour)
+	}
+	if m := u / uint64(time.Minute); m > 0 {
+		b = append(strconv.AppendUint(b, m, 10), 'M')
+		u -= m * uint64(time.Minute)
+	}
+	if u > 0 {
+		b = strconv.AppendUint(b, u/uint64(time.Second), 10)
+		if ns := u % uint64(time.Second); ns > 0 {
+			frac := strconv.FormatUint(ns+uint64(time.Second), 10)[1:]
+			b = append(b, '.')
+			b = append(b, strings.TrimRight(frac, "0")...)
+		}
+		b = append(b, 'S')
+	}
+
+	return string(b)
+}
+
+// parseISODuration parses an ISO 8601 duration of weeks, days, hours,
+// minutes and seconds. Years and months don't have a fixed length and are
+// rejected.
+func parseISODuration(s string) (time.Duration, error) {
+	var neg bool
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, ErrInvalidDuration
+	}
+	s = s[1:]
+
+	var d time.Duration
+	var inTime bool
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, ErrInvalidDuration
+			}
+			inTime, s = true, s[1:]
+			continue
+		}
+
+		i := strings.IndexAny(s, "WDHMS")
+		if i <= 0 {
+			return 0, ErrInvalidDuration
+		}
+
+		var unit time.Duration
+		switch {
+		case !inTime && s[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && s[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && s[i] == 'H':
+			unit = time.Hour
+		case inTime && s[i] == 'M':
+			unit = time.Minute
+		case inTime && s[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, ErrInvalidDuration
+		}
+
+		n, err := parseDurationNumber(s[:i], unit)
+		if err != nil || d > math.MaxInt64-n {
+			return 0, ErrInvalidDuration
+		}
+		d += n
+		s = s[i+1:]
+	}
+
+	if neg {
+		d = -d
+	}
+
+	return d, nil
+}
+
+// parseDurationNumber returns the duration of s units, where s is a decimal
+// number with a "." or "," separating its fraction.
+func parseDurationNumber(s string, unit time.Duration) (time.Duration, error) {
+	whole, frac := s, ""
+	if i := strings.IndexAny(s, ".,"); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+		if frac == "" {
+			return 0, ErrInvalidDuration
+		}
+	}
+
+	n, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil || n > uint64(math.MaxInt64/unit) {
+		return 0, ErrInvalidDuration
+	}
+	d := time.Duration(n) * unit
+
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil || strings.ContainsAny(frac, "+-eE") {
+			return 0, ErrInvalidDuration
+		}
+		d += time.Duration(math.Round(f * float64(unit)))
+	}
+
+	return d, nil
+}
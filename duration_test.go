@@ -0,0 +1,116 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurations_marshal(t *testing.T) {
+	period := 36*time.Hour + 90*time.Second + 250*time.Millisecond
+	end := Date(time.Date(2016, 8, 31, 0, 0, 0, 0, time.UTC))
+
+	out := bytes.NewBuffer(nil)
+	err := MarshalPayload(out, &Schedule{
+		ID:      "1",
+		Timeout: 1500 * time.Millisecond,
+		TTL:     90 * time.Second,
+		Period:  &period,
+		Steps:   []time.Duration{0, time.Minute, -500 * time.Millisecond},
+		Start:   Date(time.Date(2016, 8, 17, 0, 0, 0, 0, time.UTC)),
+		End:     &end,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"data":{"type":"schedules","id":"1","attributes":{` +
+		`"end":"2016-08-31",` +
+		`"period":"PT36H1M30.25S",` +
+		`"start":"2016-08-17",` +
+		`"steps":["PT0S","PT1M","-PT0.5S"],` +
+		`"timeout":1500000000,` +
+		`"ttl":90}}}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+}
+
+func TestDurations_unmarshal(t *testing.T) {
+	doc := `{"data": {"type": "schedules", "id": "1", "attributes": {
+		"timeout": 1500000000,
+		"ttl": 1.5,
+		"period": "P1DT12H1M30.25S",
+		"steps": ["PT0S", "P1W", "-PT0,5S"],
+		"start": "2016-08-17",
+		"end": "2016-08-31"
+	}}}`
+
+	schedule := new(Schedule)
+	if err := UnmarshalPayload(strings.NewReader(doc), schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	period := 36*time.Hour + 90*time.Second + 250*time.Millisecond
+	end := Date(time.Date(2016, 8, 31, 0, 0, 0, 0, time.UTC))
+	expected := &Schedule{
+		ID:      "1",
+		Timeout: 1500 * time.Millisecond,
+		TTL:     1500 * time.Millisecond,
+		Period:  &period,
+		Steps:   []time.Duration{0, 7 * 24 * time.Hour, -500 * time.Millisecond},
+		Start:   Date(time.Date(2016, 8, 17, 0, 0, 0, 0, time.UTC)),
+		End:     &end,
+	}
+	if !reflect.DeepEqual(schedule, expected) {
+		t.Fatalf("Was expecting %+v, got %+v", expected, schedule)
+	}
+}
+
+func TestDurations_invalid(t *testing.T) {
+	for _, period := range []string{`"1h"`, `"P"`, `"PT"`, `"P1Y"`, `"P1M"`, `"PT1D"`, `"PT1.S"`, `"PT-1S"`, `90`} {
+		t.Run(period, func(t *testing.T) {
+			doc := `{"data": {"type": "schedules", "attributes": {"period": ` + period + `}}}`
+
+			err := UnmarshalPayload(strings.NewReader(doc), new(Schedule))
+			if !errors.Is(err, ErrInvalidDuration) {
+				t.Fatalf("Was expecting ErrInvalidDuration, got %v", err)
+			}
+		})
+	}
+}
+
+func TestISODuration_roundTrip(t *testing.T) {
+	for _, d := range []time.Duration{
+		time.Nanosecond,
+		-time.Hour,
+		25*time.Hour + time.Microsecond,
+		1<<63 - 1,
+	} {
+		s := formatISODuration(d)
+		parsed, err := parseISODuration(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if parsed != d {
+			t.Fatalf("Was expecting %s to be parsed as %v, got %v", s, d, parsed)
+		}
+	}
+}
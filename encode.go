@@ -0,0 +1,732 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPooledBufferSize bounds the buffers kept in encodeStatePool, so that a
+// single huge document does not pin its memory.
+const maxPooledBufferSize = 1 << 20
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+var encodeStatePool = sync.Pool{
+	New: func() interface{} {
+		return &encodeState{includedSpans: map[string]span{}}
+	},
+}
+
+// encodeState writes a JSON API document straight from the cached struct
+// metadata of the models, without building the Node tree of Marshal. The
+// bytes are the same as those of encoding that tree with a json.Encoder.
+type encodeState struct {
+	buf  []byte
+	opts *options
+
+	// levels holds the scratch space of the resource being written at each
+	// depth of related resources.
+	levels []*encodeLevel
+
+	// included holds the encoded included resources; includedSpans maps their
+	// "type,id" keys to their position in included.
+	included      []byte
+	includedSpans map[string]span
+	includedKeys  []string
+}
+
+// encodeLevel is the scratch space used to write one resource object.
+type encodeLevel struct {
+	// out receives a related resource encoded on the next level.
+	out []byte
+
+	// vals holds the encoded attribute values and relationship objects,
+	// located by attrs and rels.
+	vals  []byte
+	attrs []member
+	rels  []member
+
+	// pending holds the related resources of a to-many relationship until
+	// all of them were visited.
+	pending      []byte
+	pendingNodes []pendingNode
+}
+
+type span struct {
+	start, end int
+}
+
+type member struct {
+	name string
+	span
+}
+
+type pendingNode struct {
+	typ, id string
+	span
+}
+
+func newEncodeState(o *options) *encodeState {
+	e := encodeStatePool.Get().(*encodeState)
+	e.opts = o
+
+	return e
+}
+
+func (e *encodeState) release() {
+	if cap(e.buf) > maxPooledBufferSize || cap(e.included) > maxPooledBufferSize {
+		return
+	}
+
+	e.buf = e.buf[:0]
+	e.opts = nil
+	e.included = e.included[:0]
+	e.includedKeys = e.includedKeys[:0]
+	for k := range e.includedSpans {
+		delete(e.includedSpans, k)
+	}
+	encodeStatePool.Put(e)
+}
+
+func (e *encodeState) level(depth int) *encodeLevel {
+	for len(e.levels) <= depth {
+		e.levels = append(e.levels, new(encodeLevel))
+	}
+
+	return e.levels[depth]
+}
+
+// canEncodeDirect reports whether the encodeState supports models. Anything
+// else is left to the Node tree, so that its errors are preserved.
+func canEncodeDirect(models interface{}) bool {
+	vals := reflect.ValueOf(models)
+	if vals.Kind() != reflect.Slice {
+		return true
+	}
+
+	for i := 0; i < vals.Len(); i++ {
+		v := vals.Index(i)
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Ptr || v.Type().Elem().Kind() != reflect.Struct {
+			return false
+		}
+	}
+
+	return true
+}
+
+// marshalPayload writes the document MarshalPayload writes for models.
+func (e *encodeState) marshalPayload(models interface{}) error {
+	var err error
+
+	switch vals := reflect.ValueOf(models); vals.Kind() {
+	case reflect.Slice:
+		e.buf = append(e.buf, `{"data":[`...)
+		for i := 0; i < vals.Len(); i++ {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			if e.buf, _, _, err = e.encodeResource(e.buf, vals.Index(i), 0); err != nil {
+				return err
+			}
+		}
+		e.buf = append(e.buf, ']')
+		e.writeIncluded()
+
+		if linkableModels, isLinkable := models.(Linkable); isLinkable {
+			jl := linkableModels.JSONAPILinks()
+			if er := jl.validate(); er != nil {
+				return er
+			}
+			if e.buf, err = appendMember(e.buf, "links", jl); err != nil {
+				return err
+			}
+		}
+
+		if metableModels, ok := models.(Metable); ok {
+			if e.buf, err = appendMember(e.buf, "meta", metableModels.JSONAPIMeta()); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		// Check that the pointer was to a struct
+		if reflect.Indirect(vals).Kind() != reflect.Struct {
+			return ErrUnexpectedType
+		}
+
+		e.buf = append(e.buf, `{"data":`...)
+		if e.buf, _, _, err = e.encodeResource(e.buf, vals, 0); err != nil {
+			return err
+		}
+		e.writeIncluded()
+	default:
+		return ErrUnexpectedType
+	}
+
+	e.buf = append(e.buf, "}\n"...)
+
+	return nil
+}
+
+// encodeResource appends the resource object of value, a struct pointer, to
+// dst and returns its type and id. Its related resources are added to the
+// included resources, in the order visitModelNode sideloads them.
+func (e *encodeState) encodeResource(dst []byte, value reflect.Value, depth int) ([]byte, string, string, error) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.IsNil() {
+		return append(dst, "null"...), "", "", nil
+	}
+
+	model := value.Interface()
+	modelValue := value.Elem()
+
+	info, err := getModelInfo(modelValue.Type(), e.opts.naming)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	lvl := e.level(depth)
+	lvl.vals = lvl.vals[:0]
+	lvl.attrs = lvl.attrs[:0]
+	lvl.rels = lvl.rels[:0]
+
+	var typ, id, clientID string
+
+	for _, field := range info.fields {
+		if field.access == accessWriteOnly {
+			continue
+		}
+
+		fieldValue := modelValue.Field(field.index)
+
+		switch field.annotation {
+		case annotationPrimary:
+			if id, err = formatID(fieldValue); err != nil {
+				return nil, "", "", err
+			}
+			typ = field.name
+		case annotationClientID:
+			if s := fieldValue.String(); s != "" {
+				clientID = s
+			}
+		case annotationAttribute:
+			start := len(lvl.vals)
+			var ok bool
+			if lvl.vals, ok, err = appendAttribute(lvl.vals, fieldValue, field, e.opts); err != nil {
+				return nil, "", "", err
+			}
+			if ok {
+				lvl.attrs = append(lvl.attrs, member{field.name, span{start, len(lvl.vals)}})
+			}
+		case annotationRelation:
+			start := len(lvl.vals)
+			var ok bool
+			if ok, err = e.appendRelationship(model, fieldValue, field, depth); err != nil {
+				return nil, "", "", err
+			}
+			if ok {
+				lvl.rels = append(lvl.rels, member{field.name, span{start, len(lvl.vals)}})
+			}
+		}
+	}
+
+	dst = append(dst, `{"type":`...)
+	dst = appendString(dst, typ)
+	if id != "" {
+		dst = append(dst, `,"id":`...)
+		dst = appendString(dst, id)
+	}
+	if clientID != "" {
+		dst = append(dst, `,"client-id":`...)
+		dst = appendString(dst, clientID)
+	}
+	dst = appendMembers(dst, "attributes", lvl.vals, lvl.attrs)
+	dst = appendMembers(dst, "relationships", lvl.vals, lvl.rels)
+
+	if linkableModel, isLinkable := model.(Linkable); isLinkable {
+		jl := linkableModel.JSONAPILinks()
+		if er := jl.validate(); er != nil {
+			return nil, "", "", er
+		}
+		if dst, err = appendMember(dst, "links", jl); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	if metableModel, ok := model.(Metable); ok {
+		if dst, err = appendMember(dst, "meta", metableModel.JSONAPIMeta()); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	return append(dst, '}'), typ, id, nil
+}
+
+// appendRelationship appends the relationship object of fieldValue to the
+// values of the resource at depth, and reports whether it was written.
+func (e *encodeState) appendRelationship(model interface{}, fieldValue reflect.Value,
+	field *fieldInfo, depth int) (bool, error) {
+	omitEmpty := field.hasOption(annotationOmitEmpty)
+
+	isSlice := fieldValue.Type().Kind() == reflect.Slice
+	if omitEmpty &&
+		(isSlice && fieldValue.Len() < 1 ||
+			(!isSlice && isNullRelation(fieldValue))) {
+		return false, nil
+	}
+
+	var relLinks *Links
+	if linkableModel, ok := model.(RelationshipLinkable); ok {
+		relLinks = linkableModel.JSONAPIRelationshipLinks(field.name)
+	}
+
+	var relMeta *Meta
+	if metableModel, ok := model.(RelationshipMetable); ok {
+		relMeta = metableModel.JSONAPIRelationshipMeta(field.name)
+	}
+
+	lvl := e.level(depth)
+	next := e.level(depth + 1)
+	var err error
+
+	switch {
+	case field.linkageType != "" && isSlice:
+		lvl.vals = append(lvl.vals, `{"data":[`...)
+		for i := 0; i < fieldValue.Len(); i++ {
+			id, err := formatID(fieldValue.Index(i))
+			if err != nil {
+				return false, err
+			}
+
+			if i > 0 {
+				lvl.vals = append(lvl.vals, ',')
+			}
+			lvl.vals = appendIdentifier(lvl.vals, field.linkageType, id)
+		}
+		lvl.vals = append(lvl.vals, ']')
+	case field.linkageType != "":
+		// Handle null relationship case
+		if isNullRelation(fieldValue) {
+			lvl.vals = append(lvl.vals, `{"data":null}`...)
+			return true, nil
+		}
+
+		id, err := formatID(fieldValue)
+		if err != nil {
+			return false, err
+		}
+
+		lvl.vals = append(lvl.vals, `{"data":`...)
+		lvl.vals = appendIdentifier(lvl.vals, field.linkageType, id)
+	case isSlice:
+		// Like visitModelNode, visit every related resource before any of
+		// them is included.
+		lvl.pending = lvl.pending[:0]
+		lvl.pendingNodes = lvl.pendingNodes[:0]
+
+		lvl.vals = append(lvl.vals, `{"data":[`...)
+		for i := 0; i < fieldValue.Len(); i++ {
+			elem := fieldValue.Index(i)
+			if elem.Kind() == reflect.Ptr && elem.IsNil() {
+				// Like visitModelNodeRelationships, skip nil related models
+				continue
+			}
+
+			var typ, id string
+			if next.out, typ, id, err = e.encodeResource(next.out[:0], relatedModel(elem), depth+1); err != nil {
+				return false, err
+			}
+
+			if len(lvl.pendingNodes) > 0 {
+				lvl.vals = append(lvl.vals, ',')
+			}
+
+			start := len(lvl.pending)
+			lvl.pending = append(lvl.pending, next.out...)
+			lvl.pendingNodes = append(lvl.pendingNodes, pendingNode{typ, id, span{start, len(lvl.pending)}})
+
+			lvl.vals = appendIdentifier(lvl.vals, typ, id)
+		}
+		lvl.vals = append(lvl.vals, ']')
+
+		for _, n := range lvl.pendingNodes {
+			e.include(n.typ, n.id, lvl.pending[n.start:n.end])
+		}
+	default:
+		// Handle null relationship case
+		if isNullRelation(fieldValue) {
+			lvl.vals = append(lvl.vals, `{"data":null}`...)
+			return true, nil
+		}
+
+		var typ, id string
+		if next.out, typ, id, err = e.encodeResource(next.out[:0], relatedModel(fieldValue), depth+1); err != nil {
+			return false, err
+		}
+		e.include(typ, id, next.out)
+
+		lvl.vals = append(lvl.vals, `{"data":`...)
+		lvl.vals = appendIdentifier(lvl.vals, typ, id)
+	}
+
+	if lvl.vals, err = appendMember(lvl.vals, "links", relLinks); err != nil {
+		return false, err
+	}
+	if lvl.vals, err = appendMember(lvl.vals, "meta", relMeta); err != nil {
+		return false, err
+	}
+	lvl.vals = append(lvl.vals, '}')
+
+	return true, nil
+}
+
+// include adds the encoded resource b to the included resources unless a
+// resource with the same type and id was already included.
+func (e *encodeState) include(typ, id string, b []byte) {
+	if b == nil || string(b) == "null" {
+		return
+	}
+
+	key := typ + "," + id
+	if _, ok := e.includedSpans[key]; ok {
+		return
+	}
+
+	start := len(e.included)
+	e.included = append(e.included, b...)
+	e.includedSpans[key] = span{start, len(e.included)}
+	e.includedKeys = append(e.includedKeys, key)
+}
+
+// writeIncluded writes the "included" member, ordered like nodeMapValues.
+func (e *encodeState) writeIncluded() {
+	if len(e.includedKeys) == 0 {
+		return
+	}
+	sort.Strings(e.includedKeys)
+
+	e.buf = append(e.buf, `,"included":[`...)
+	for i, key := range e.includedKeys {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		s := e.includedSpans[key]
+		e.buf = append(e.buf, e.included[s.start:s.end]...)
+	}
+	e.buf = append(e.buf, ']')
+}
+
+// appendIdentifier appends the resource identifier object toShallowNode
+// produces.
+func appendIdentifier(dst []byte, typ, id string) []byte {
+	dst = append(dst, `{"type":`...)
+	dst = appendString(dst, typ)
+	if id != "" {
+		dst = append(dst, `,"id":`...)
+		dst = appendString(dst, id)
+	}
+
+	return append(dst, '}')
+}
+
+// appendMembers appends an object member named name holding members, whose
+// values are located in vals. Like encoding/json does for maps, the members
+// are sorted by name, and the last of several members with the same name
+// wins. Nothing is written when there are no members.
+func appendMembers(dst []byte, name string, vals []byte, members []member) []byte {
+	if len(members) == 0 {
+		return dst
+	}
+
+	// Insertion sort keeps the struct order of equal names without
+	// allocating; resources have few members.
+	for i := 1; i < len(members); i++ {
+		for j := i; j > 0 && members[j].name < members[j-1].name; j-- {
+			members[j], members[j-1] = members[j-1], members[j]
+		}
+	}
+
+	dst = append(dst, ',')
+	dst = appendString(dst, name)
+	dst = append(dst, ":{"...)
+	for i, m := range members {
+		if i+1 < len(members) && members[i+1].name == m.name {
+			continue
+		}
+		if dst[len(dst)-1] != '{' {
+			dst = append(dst, ',')
+		}
+		dst = appendString(dst, m.name)
+		dst = append(dst, ':')
+		dst = append(dst, vals[m.start:m.end]...)
+	}
+
+	return append(dst, '}')
+}
+
+// appendMember appends a member named name holding v, a *Links or *Meta,
+// unless v is nil.
+func appendMember(dst []byte, name string, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case *Links:
+		if v == nil {
+			return dst, nil
+		}
+	case *Meta:
+		if v == nil {
+			return dst, nil
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, ',')
+	dst = appendString(dst, name)
+	dst = append(dst, ':')
+
+	return append(dst, b...), nil
+}
+
+// appendAttribute appends the value of the attribute fieldValue, as
+// visitModelNode encodes it, and reports whether it was written.
+func appendAttribute(dst []byte, fieldValue reflect.Value, field *fieldInfo, o *options) ([]byte, bool, error) {
+	var omitEmpty bool
+
+	if len(field.args) > 2 {
+		for _, arg := range field.args[2:] {
+			if arg == annotationOmitEmpty {
+				omitEmpty = true
+			}
+		}
+	}
+	format := parseTimeFormat(field.args)
+
+	switch t := fieldValue.Type(); {
+	case t == timeType:
+		// The model is addressable, and taking the address of the field
+		// saves boxing the time.
+		tm := *fieldValue.Addr().Interface().(*time.Time)
+		if tm.IsZero() {
+			return dst, false, nil
+		}
+
+		return format.append(dst, tm), true, nil
+	case isTimeType(t):
+		tm := timeValue(fieldValue)
+		if tm.IsZero() {
+			return dst, false, nil
+		}
+
+		return format.append(dst, tm), true, nil
+	case t.Kind() == reflect.Ptr && isTimeType(t.Elem()):
+		// A time pointer may be nil
+		if fieldValue.IsNil() {
+			if omitEmpty {
+				return dst, false, nil
+			}
+
+			return append(dst, "null"...), true, nil
+		}
+
+		tm := timeValue(fieldValue.Elem())
+		if tm.IsZero() && omitEmpty {
+			return dst, false, nil
+		}
+
+		return format.append(dst, tm), true, nil
+	}
+
+	// See if we need to omit this field
+	if omitEmpty && isEmptyValue(fieldValue) {
+		return dst, false, nil
+	}
+
+	if field.composite {
+		v, err := attributeValue(fieldValue, format, o)
+		if err != nil {
+			return nil, false, err
+		}
+		fieldValue = reflect.ValueOf(&v).Elem()
+	}
+
+	dst, err := appendValue(dst, fieldValue, field.direct)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return dst, true, nil
+}
+
+// isEmptyValue reports whether v is deeply equal to the zero value of its
+// type, as visitModelNode checks for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	default:
+		return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+	}
+}
+
+// isDirectType reports whether appendValue can write values of type t, or
+// pointers to them, without encoding/json.
+func isDirectType(t reflect.Type) bool {
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return false
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// appendValue appends the JSON encoding of v. Values of a direct type are
+// written without encoding/json.
+func appendValue(dst []byte, v reflect.Value, direct bool) ([]byte, error) {
+	if direct {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return append(dst, "null"...), nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Bool:
+			return strconv.AppendBool(dst, v.Bool()), nil
+		case reflect.String:
+			return appendString(dst, v.String()), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.AppendInt(dst, v.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.AppendUint(dst, v.Uint(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			if f := v.Float(); !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return appendFloat(dst, f, v.Type().Bits()), nil
+			}
+		}
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(dst, b...), nil
+}
+
+// appendFloat formats f like encoding/json: as ES6 does, it uses the
+// exponent format only for very small and very large numbers.
+func appendFloat(dst []byte, f float64, bits int) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+
+	return dst
+}
+
+// appendString appends s as a JSON string. Strings that need escaping are
+// left to encoding/json, so that they are escaped the same way.
+func appendString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+				return appendStringSlow(dst, s)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 || r == '\u2028' || r == '\u2029' {
+			return appendStringSlow(dst, s)
+		}
+		i += size
+	}
+
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+
+	return append(dst, '"')
+}
+
+func appendStringSlow(dst []byte, s string) []byte {
+	// Marshaling a string never fails.
+	b, _ := json.Marshal(s)
+
+	return append(dst, b...)
+}
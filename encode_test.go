@@ -0,0 +1,256 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// marshalNodeTree writes models the way MarshalPayload did before it wrote
+// JSON directly: by encoding the Node tree returned by Marshal.
+func marshalNodeTree(w io.Writer, models interface{}, opts ...Option) error {
+	payload, err := Marshal(models, opts...)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(payload)
+}
+
+func TestMarshalPayload_matchesNodeTree(t *testing.T) {
+	description, pages := "A <b>bold</b> \"story\"\n", uint(120)
+	id, make := "123e4567-e89b-12d3-a456-426655440000", "Ford"
+	intPtr := CustomIntType(7)
+	hiredAt := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	second := testBlog()
+	second.ID = 6
+	second.Posts[0].Comments[0].Body = "a different copy of comment 1"
+
+	for name, models := range map[string]interface{}{
+		"one":        testBlog(),
+		"many":       []*Blog{testBlog(), second},
+		"interfaces": []interface{}{testBlog(), &Book{ID: 1}},
+		"empty":      []*Blog{},
+		"nil element": []interface{}{
+			(*Blog)(nil),
+		},
+		"nil related elements": &Blog{
+			ID:    1,
+			Posts: []*Post{nil, {ID: 2, Title: "Foo"}, nil},
+		},
+		"only nil related elements": &Blog{
+			ID:    1,
+			Posts: []*Post{nil},
+		},
+		"pointers": &Book{
+			ID:          1,
+			Author:      "Zoë   & co",
+			Description: &description,
+			Pages:       &pages,
+			Tags:        []string{"a", "<b>"},
+		},
+		"omitempty": &Book{ID: 2},
+		"id pointer": &Car{
+			ID:   &id,
+			Make: &make,
+		},
+		"times": &TimestampModel{
+			ID:       1,
+			DefaultV: hiredAt,
+			ISO8601V: hiredAt,
+			ISO8601P: &hiredAt,
+			RFC3339V: hiredAt,
+		},
+		"time formats": &TimeFormats{
+			ID:    "1",
+			Nano:  hiredAt.Add(123 * time.Nanosecond),
+			Milli: hiredAt,
+			Micro: &hiredAt,
+			Date:  hiredAt,
+			Zoned: hiredAt.In(time.FixedZone("", 2*60*60)),
+			Dates: []time.Time{hiredAt},
+		},
+		"durations": &Schedule{
+			ID:      "1",
+			Timeout: 1500 * time.Millisecond,
+			TTL:     90 * time.Second,
+			Steps:   []time.Duration{time.Minute, -time.Second},
+			Start:   Date(hiredAt),
+		},
+		"nullables": &Nullables{
+			ID:      "1",
+			Name:    sql.NullString{String: "Jane", Valid: true},
+			Deleted: sql.NullTime{Time: hiredAt, Valid: true},
+			Flags:   []sql.NullBool{{Bool: true, Valid: true}, {}},
+		},
+		"nested attributes": &Company{
+			ID:   "1",
+			Name: "Acme",
+			Boss: Employee{Firstname: "Jane", HiredAt: &hiredAt},
+			Teams: []Team{
+				{Name: "Core", Members: []Employee{{Firstname: "John", Age: 30}}},
+			},
+			FoundedAt: hiredAt,
+		},
+		"custom types": &CustomAttributeTypes{
+			ID:     "1",
+			Int:    3,
+			IntPtr: &intPtr,
+			Float:  1e-7,
+			String: "custom",
+		},
+		"floats": &WithPointer{
+			FloatVal: func() *float32 { f := float32(0.1); return &f }(),
+		},
+		"client id": &Post{ClientID: "abc", Title: "Foo"},
+		"slices": &SliceAttributes{
+			ID:     "1",
+			Ints:   []int{1, 2},
+			Times:  []time.Time{hiredAt},
+			Matrix: [][]string{{"a"}},
+			Data:   []byte("hi"),
+		},
+		"json tags": &Office{
+			ID:      "1",
+			Address: Address{Street: "Main <Street>"},
+			Manager: &Employee{Firstname: "Jane"},
+			Staff:   map[string]Employee{"cto": {HiredAt: &hiredAt}},
+		},
+		"maps": &MapAttributes{
+			ID:     "1",
+			Counts: map[string]int{"b": 2, "a": 1},
+			Scores: map[int]float64{7: 0.5},
+			Times:  map[string]*time.Time{"start": &hiredAt, "end": nil},
+			Nested: map[string]map[string]int{"x": {"y": 3}},
+		},
+		"large float": &CustomAttributeTypes{
+			ID:    "2",
+			Float: 1e21,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			want := bytes.NewBuffer(nil)
+			wantErr := marshalNodeTree(want, models)
+
+			got := bytes.NewBuffer(nil)
+			gotErr := MarshalPayload(got, models)
+
+			if (wantErr == nil) != (gotErr == nil) {
+				t.Fatalf("expected error %v, got %v", wantErr, gotErr)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Fatalf("output differs from the Node tree:\ngot:  %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalPayload_nilRelatedElements(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, &Blog{ID: 1, Posts: []*Post{nil, {ID: 2}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Data struct {
+			Relationships struct {
+				Posts struct {
+					Data []map[string]string `json:"data"`
+				} `json:"posts"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []map[string]string{{"type": "posts", "id": "2"}}, doc.Data.Relationships.Posts.Data; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the nil posts to be skipped, got %v", a)
+	}
+}
+
+func TestMarshalPayload_errorsMatchNodeTree(t *testing.T) {
+	for name, models := range map[string]interface{}{
+		"bad id": &struct {
+			ID float64 `jsonapi:"primary,floats"`
+		}{},
+		"bad links":       &BadComment{ID: 1},
+		"unexpected type": "blogs",
+		"pointer to int":  new(int),
+		"unsupported float": &CustomAttributeTypes{
+			ID:    "1",
+			Float: CustomFloatType(math.Inf(1)),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			wantErr := marshalNodeTree(io.Discard, models)
+			gotErr := MarshalPayload(io.Discard, models)
+
+			if wantErr == nil || gotErr == nil || wantErr.Error() != gotErr.Error() {
+				t.Fatalf("expected error %v, got %v", wantErr, gotErr)
+			}
+		})
+	}
+}
+
+// benchmarkModels returns blogs, whose links and meta dominate the cost of
+// marshaling them, and books, which have attributes only.
+func benchmarkModels() map[string]interface{} {
+	blogs := make([]*Blog, 100)
+	books := make([]*Book, 100)
+	description := "A book"
+	for i := range blogs {
+		blogs[i] = testBlog()
+		blogs[i].ID = i
+		books[i] = &Book{
+			ID:          uint64(i),
+			Author:      "Author",
+			ISBN:        "978-3-16-148410-0",
+			Title:       "Title",
+			Description: &description,
+			Tags:        []string{"fiction"},
+		}
+	}
+
+	return map[string]interface{}{"blogs": blogs, "books": books}
+}
+
+func benchmarkMarshal(b *testing.B, marshal func(io.Writer, interface{}, ...Option) error) {
+	for name, models := range benchmarkModels() {
+		models := models
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := marshal(io.Discard, models); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshalPayload(b *testing.B) {
+	benchmarkMarshal(b, MarshalPayload)
+}
+
+func BenchmarkMarshalPayload_nodeTree(b *testing.B) {
+	benchmarkMarshal(b, marshalNodeTree)
+}
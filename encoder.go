@@ -0,0 +1,434 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrEncoderClosed is returned when writing to an Encoder after its document
+// has been closed.
+var ErrEncoderClosed = errors.New("jsonapi: encoder is closed")
+
+// Encoder writes JSON API documents to an output stream. The marshal
+// functions of the package are shortcuts for an Encoder with the default
+// settings: compact, HTML-escaped output followed by a newline.
+//
+// Output is canonical: the members of attributes, relationships, links and
+// meta objects are sorted by name, as encoding/json does for maps, and the
+// "included" resources are sorted by type and id.
+//
+// Besides writing complete documents with Encode and the other Encode
+// methods, an Encoder can stream a many-payload: every call to WriteData
+// writes one resource object to the "data" array as soon as it is produced,
+// and Close completes the document with the deduplicated "included" resources
+// and the top-level "links" and "meta". Only the related resources are held
+// in memory until Close, so the memory used for primary data stays bounded
+// however many resources are written.
+//
+//	enc := jsonapi.NewEncoder(w)
+//	for rows.Next() {
+//		if err := enc.WriteData(scanBlog(rows)); err != nil {
+//			return err
+//		}
+//	}
+//	return enc.Close()
+//
+// The streamed document is the same as the one Encode writes for a slice of
+// the written models. An Encoder is not safe for concurrent use.
+type Encoder struct {
+	w    io.Writer
+	opts *options
+
+	prefix          string
+	indent          string
+	escapeHTML      bool
+	trailingNewline bool
+
+	included     map[string]*Node
+	includedKeys []string
+
+	links *Links
+	meta  *Meta
+
+	started bool
+	closed  bool
+	err     error
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	return &Encoder{
+		w:               w,
+		opts:            newOptions(opts),
+		escapeHTML:      true,
+		trailingNewline: true,
+		included:        map[string]*Node{},
+	}
+}
+
+// SetIndent makes the encoder format each document as if indented by
+// json.Indent with the given prefix and indent. Calling SetIndent("", "")
+// disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters, such as the &
+// in the query of a link, should be escaped inside JSON strings. The default
+// is true, like encoding/json. With a codec set by WithCodec, escaping is left
+// to the codec.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
+
+// SetTrailingNewline specifies whether every document written by the Encode
+// methods and Close is followed by a newline. The default is true, like
+// encoding/json, which separates documents written to the same stream.
+func (enc *Encoder) SetTrailingNewline(on bool) {
+	enc.trailingNewline = on
+}
+
+// Encode writes the document MarshalPayload writes for models: one or many
+// resources, with their related resources sideloaded into "included".
+func (enc *Encoder) Encode(models interface{}) error {
+	if enc.isDefault() && canEncodeDirect(models) {
+		e := newEncodeState(enc.opts)
+		defer e.release()
+
+		if err := e.marshalPayload(models); err != nil {
+			return err
+		}
+
+		b := e.buf
+		if !enc.trailingNewline {
+			b = bytes.TrimSuffix(b, []byte("\n"))
+		}
+
+		_, err := enc.w.Write(b)
+		return err
+	}
+
+	payload, err := marshal(models, enc.opts)
+	if err != nil {
+		return err
+	}
+
+	return enc.encode(payload)
+}
+
+// EncodeWithoutIncluded writes the document MarshalPayloadWithoutIncluded
+// writes for models, without the "included" resources.
+func (enc *Encoder) EncodeWithoutIncluded(models interface{}) error {
+	payload, err := marshal(models, enc.opts)
+	if err != nil {
+		return err
+	}
+	payload.clearIncluded()
+
+	return enc.encode(payload)
+}
+
+// EncodeEmbedded writes the document MarshalOnePayloadEmbedded writes for
+// model, with its related resources embedded in the relationships. It
+// returns ErrUnexpectedType if model is not a struct pointer.
+func (enc *Encoder) EncodeEmbedded(model interface{}) error {
+	if !isStructPointer(model) {
+		return ErrUnexpectedType
+	}
+
+	rootNode, err := visitModelNode(model, nil, false, enc.opts)
+	if err != nil {
+		return err
+	}
+
+	return enc.encode(&OnePayload{Data: rootNode})
+}
+
+// EncodeErrors writes the errors document MarshalErrors writes.
+func (enc *Encoder) EncodeErrors(errorObjects []*ErrorObject) error {
+	return enc.encode(&ErrorsPayload{Errors: errorObjects})
+}
+
+// SetLinks sets the top-level "links" of the streamed document. It may be
+// called at any time before Close.
+func (enc *Encoder) SetLinks(links *Links) {
+	enc.links = links
+}
+
+// SetMeta sets the top-level "meta" of the streamed document. It may be
+// called at any time before Close.
+func (enc *Encoder) SetMeta(meta *Meta) {
+	enc.meta = meta
+}
+
+// WriteData writes model, a struct pointer, as the next resource object of
+// the "data" array. Its relations are sideloaded into "included" when the
+// document is closed. ErrUnexpectedType is returned for any other value,
+// without writing anything.
+//
+// Once an error occurred, the output is incomplete and every subsequent call
+// returns the same error.
+func (enc *Encoder) WriteData(model interface{}) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	if enc.closed {
+		return ErrEncoderClosed
+	}
+	// Nothing is written for a model of the wrong type, so the encoder can
+	// still be used
+	if !isStructPointer(model) {
+		return ErrUnexpectedType
+	}
+
+	included := map[string]*Node{}
+	node, err := visitModelNode(model, &included, true, enc.opts)
+	if err != nil {
+		return enc.fail(err)
+	}
+	for _, n := range included {
+		enc.include(n)
+	}
+
+	b, err := enc.marshalValue(node, 2)
+	if err != nil {
+		return enc.fail(err)
+	}
+
+	prefix := ","
+	if !enc.started {
+		prefix = "{" + enc.newline(1) + `"data":` + enc.space() + "["
+		enc.started = true
+	}
+
+	return enc.write([]byte(prefix+enc.newline(2)), b)
+}
+
+// Close completes the streamed document: it ends the "data" array and writes
+// the "included" resources, "links" and "meta". Closing an Encoder to which
+// no data was written produces a document with an empty "data" array.
+func (enc *Encoder) Close() error {
+	if enc.err != nil {
+		return enc.err
+	}
+	if enc.closed {
+		return ErrEncoderClosed
+	}
+	enc.closed = true
+
+	end := enc.newline(1) + "]"
+	if !enc.started {
+		end = "{" + enc.newline(1) + `"data":` + enc.space() + "[]"
+	}
+	if err := enc.write([]byte(end)); err != nil {
+		return err
+	}
+
+	if len(enc.includedKeys) > 0 {
+		sort.Strings(enc.includedKeys)
+
+		if err := enc.write([]byte("," + enc.newline(1) + `"included":` + enc.space() + "[")); err != nil {
+			return err
+		}
+		for i, key := range enc.includedKeys {
+			b, err := enc.marshalValue(enc.included[key], 2)
+			if err != nil {
+				return enc.fail(err)
+			}
+			sep := enc.newline(2)
+			if i > 0 {
+				sep = "," + sep
+			}
+			if err := enc.write([]byte(sep), b); err != nil {
+				return err
+			}
+		}
+		if err := enc.write([]byte(enc.newline(1) + "]")); err != nil {
+			return err
+		}
+	}
+
+	if enc.links != nil {
+		if err := enc.links.validate(); err != nil {
+			return enc.fail(err)
+		}
+		if err := enc.writeMember("links", enc.links); err != nil {
+			return err
+		}
+	}
+	if enc.meta != nil {
+		if err := enc.writeMember("meta", enc.meta); err != nil {
+			return err
+		}
+	}
+
+	return enc.write([]byte(enc.newline(0) + "}" + enc.end()))
+}
+
+// MarshalStream writes a many-payload whose "data" array holds every model
+// received from models, until the channel is closed, and then closes the
+// document.
+func (enc *Encoder) MarshalStream(models <-chan interface{}) error {
+	for model := range models {
+		if err := enc.WriteData(model); err != nil {
+			for range models {
+				// Drain the channel so that the producer is not blocked forever.
+			}
+			return err
+		}
+	}
+
+	return enc.Close()
+}
+
+// isDefault reports whether the output may be written directly by an
+// encodeState.
+func (enc *Encoder) isDefault() bool {
+	return enc.opts.codec == nil && enc.escapeHTML && !enc.indented()
+}
+
+func (enc *Encoder) indented() bool {
+	return enc.prefix != "" || enc.indent != ""
+}
+
+// encode writes v as a complete document.
+func (enc *Encoder) encode(v interface{}) error {
+	if enc.opts.codec == nil && enc.trailingNewline {
+		je := json.NewEncoder(enc.w)
+		je.SetEscapeHTML(enc.escapeHTML)
+		je.SetIndent(enc.prefix, enc.indent)
+		return je.Encode(v)
+	}
+
+	b, err := enc.marshalValue(v, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = enc.w.Write(append(b, enc.end()...))
+	return err
+}
+
+// end returns what follows a complete document.
+func (enc *Encoder) end() string {
+	if !enc.trailingNewline {
+		return ""
+	}
+
+	return "\n"
+}
+
+// marshalValue returns the encoding of v, indented for the given depth
+// within the document.
+func (enc *Encoder) marshalValue(v interface{}, depth int) ([]byte, error) {
+	var b []byte
+	if enc.opts.codec != nil {
+		var err error
+		if b, err = enc.opts.codec.Marshal(v); err != nil {
+			return nil, err
+		}
+	} else {
+		buf := bytes.NewBuffer(nil)
+		je := json.NewEncoder(buf)
+		je.SetEscapeHTML(enc.escapeHTML)
+		if err := je.Encode(v); err != nil {
+			return nil, err
+		}
+		b = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+
+	if !enc.indented() {
+		return b, nil
+	}
+
+	indented := bytes.NewBuffer(nil)
+	if err := json.Indent(indented, b, enc.prefix+strings.Repeat(enc.indent, depth), enc.indent); err != nil {
+		return nil, err
+	}
+
+	return indented.Bytes(), nil
+}
+
+// newline returns the line break and indentation preceding a value at the
+// given depth, or "" when the output is compact.
+func (enc *Encoder) newline(depth int) string {
+	if !enc.indented() {
+		return ""
+	}
+
+	return "\n" + enc.prefix + strings.Repeat(enc.indent, depth)
+}
+
+// space returns the space following the colon of a member when the output is
+// indented.
+func (enc *Encoder) space() string {
+	if !enc.indented() {
+		return ""
+	}
+
+	return " "
+}
+
+// include adds n to the included resources unless a resource with the same
+// type and id was already included.
+func (enc *Encoder) include(n *Node) {
+	key := fmt.Sprintf("%s,%s", n.Type, n.ID)
+	if _, ok := enc.included[key]; ok {
+		return
+	}
+
+	enc.included[key] = n
+	enc.includedKeys = append(enc.includedKeys, key)
+}
+
+func (enc *Encoder) writeMember(name string, value interface{}) error {
+	b, err := enc.marshalValue(value, 1)
+	if err != nil {
+		return enc.fail(err)
+	}
+
+	return enc.write([]byte(fmt.Sprintf(",%s%q:%s", enc.newline(1), name, enc.space())), b)
+}
+
+func (enc *Encoder) write(chunks ...[]byte) error {
+	for _, b := range chunks {
+		if _, err := enc.w.Write(b); err != nil {
+			return enc.fail(err)
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) fail(err error) error {
+	enc.err = err
+	return err
+}
+
+// isStructPointer reports whether model is a non-nil pointer to a struct.
+func isStructPointer(model interface{}) bool {
+	v := reflect.ValueOf(model)
+
+	return v.Kind() == reflect.Ptr && reflect.Indirect(v).Kind() == reflect.Struct
+}
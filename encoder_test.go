@@ -0,0 +1,314 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEncoder_empty(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := NewEncoder(out).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "{\"data\":[]}\n"; out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestEncoder_linksAndMeta(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	enc := NewEncoder(out)
+	enc.SetLinks(&Links{"next": "https://example.com/blogs?page=2"})
+	enc.SetMeta(&Meta{"total": 1})
+	if err := enc.WriteData(&Blog{ID: 1, Title: "Title"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload ManyPayload
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Links == nil || (*payload.Links)["next"] != "https://example.com/blogs?page=2" {
+		t.Fatalf("expected the top-level links to be written, got %s", out.String())
+	}
+	if payload.Meta == nil || (*payload.Meta)["total"] != float64(1) {
+		t.Fatalf("expected the top-level meta to be written, got %s", out.String())
+	}
+}
+
+func TestEncoder_MarshalStream(t *testing.T) {
+	models := make(chan interface{})
+	go func() {
+		defer close(models)
+		for i := 1; i <= 3; i++ {
+			models <- &Blog{ID: i, Title: fmt.Sprintf("Title %d", i)}
+		}
+	}()
+
+	out := bytes.NewBuffer(nil)
+	if err := NewEncoder(out).MarshalStream(models); err != nil {
+		t.Fatal(err)
+	}
+
+	blogs, err := UnmarshalManyPayload(out, reflect.TypeOf(new(Blog)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 3 {
+		t.Fatalf("expected 3 blogs, got %d", len(blogs))
+	}
+	if blog := blogs[2].(*Blog); blog.ID != 3 || blog.Title != "Title 3" {
+		t.Fatalf("unexpected blog %+v", blog)
+	}
+}
+
+func TestEncoder_MarshalStream_drainsOnError(t *testing.T) {
+	models := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(models)
+		models <- &BadModel{}
+		models <- &Blog{ID: 1}
+	}()
+
+	err := NewEncoder(bytes.NewBuffer(nil)).MarshalStream(models)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	<-done
+}
+
+func TestEncoder_errorsPersist(t *testing.T) {
+	enc := NewEncoder(bytes.NewBuffer(nil))
+	if err := enc.WriteData(&BadModel{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := enc.WriteData(&Blog{ID: 1}); err == nil {
+		t.Fatal("expected the previous error to be returned")
+	}
+	if err := enc.Close(); err == nil {
+		t.Fatal("expected the previous error to be returned")
+	}
+}
+
+func TestEncoder_unexpectedType(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	enc := NewEncoder(out)
+
+	for _, model := range []interface{}{Blog{ID: 1}, (*Blog)(nil), []*Blog{{ID: 1}}, nil} {
+		if err := enc.WriteData(model); !errors.Is(err, ErrUnexpectedType) {
+			t.Fatalf("Was expecting ErrUnexpectedType writing %T, got %v", model, err)
+		}
+		if err := NewEncoder(bytes.NewBuffer(nil)).EncodeEmbedded(model); !errors.Is(err, ErrUnexpectedType) {
+			t.Fatalf("Was expecting ErrUnexpectedType encoding %T, got %v", model, err)
+		}
+	}
+
+	// Nothing was written, and the encoder still works
+	if err := enc.WriteData(&Blog{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if violations, err := Validate(out); err != nil || len(violations) > 0 {
+		t.Fatalf("Was expecting a valid document, got %v %v", violations, err)
+	}
+}
+
+func TestEncoder_closed(t *testing.T) {
+	enc := NewEncoder(bytes.NewBuffer(nil))
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.WriteData(&Blog{ID: 1}); !errors.Is(err, ErrEncoderClosed) {
+		t.Fatalf("expected ErrEncoderClosed, got %v", err)
+	}
+	if err := enc.Close(); !errors.Is(err, ErrEncoderClosed) {
+		t.Fatalf("expected ErrEncoderClosed, got %v", err)
+	}
+}
+
+func TestEncoder_streamMatchesEncode(t *testing.T) {
+	second := testBlog()
+	second.ID = 6
+	models := []*Blog{testBlog(), second}
+
+	for name, configure := range map[string]func(*Encoder){
+		"default":  func(*Encoder) {},
+		"indented": func(enc *Encoder) { enc.SetIndent("", "  ") },
+		"prefixed": func(enc *Encoder) { enc.SetIndent("> ", "\t") },
+		"unescaped": func(enc *Encoder) {
+			enc.SetIndent("", " ")
+			enc.SetEscapeHTML(false)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expected := bytes.NewBuffer(nil)
+			enc := NewEncoder(expected)
+			configure(enc)
+			if err := enc.Encode(models); err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.NewBuffer(nil)
+			enc = NewEncoder(out)
+			configure(enc)
+			for _, model := range models {
+				if err := enc.WriteData(model); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(out.Bytes(), expected.Bytes()) {
+				t.Fatalf("stream differs from Encode:\n%s\n%s", out, expected)
+			}
+		})
+	}
+}
+
+func TestEncoder_emptyIndented(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	enc := NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bytes.NewBuffer(nil)
+	enc = NewEncoder(expected)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode([]*Blog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != expected.String() {
+		t.Fatalf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestEncoder_SetEscapeHTML(t *testing.T) {
+	book := &Book{ID: 1, Title: "Q&A <draft>"}
+
+	out := bytes.NewBuffer(nil)
+	if err := NewEncoder(out).Encode(book); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"Q\u0026A \u003cdraft\u003e"`)) {
+		t.Fatalf("expected HTML characters to be escaped by default, got %s", out)
+	}
+
+	out.Reset()
+	enc := NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(book); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"Q&A <draft>"`)) {
+		t.Fatalf("expected HTML characters to be left as is, got %s", out)
+	}
+}
+
+func TestEncoder_entryPointsShareSettings(t *testing.T) {
+	for name, encode := range map[string]func(*Encoder) error{
+		"Encode":                func(enc *Encoder) error { return enc.Encode(testBlog()) },
+		"EncodeWithoutIncluded": func(enc *Encoder) error { return enc.EncodeWithoutIncluded(testBlog()) },
+		"EncodeEmbedded":        func(enc *Encoder) error { return enc.EncodeEmbedded(testBlog()) },
+		"EncodeErrors": func(enc *Encoder) error {
+			return enc.EncodeErrors([]*ErrorObject{{Title: "Bad"}})
+		},
+		"with codec": func(enc *Encoder) error {
+			enc.opts.codec = new(countingCodec)
+			return enc.Encode(testBlog())
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			enc := NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := encode(enc); err != nil {
+				t.Fatal(err)
+			}
+
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, bytes.TrimSpace(out.Bytes()), "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			if indented.String()+"\n" != out.String() {
+				t.Fatalf("expected indented output, got %s", out)
+			}
+		})
+	}
+}
+
+func TestEncoder_SetTrailingNewline(t *testing.T) {
+	for name, encode := range map[string]func(*Encoder) error{
+		"Encode":                func(enc *Encoder) error { return enc.Encode(testBlog()) },
+		"EncodeWithoutIncluded": func(enc *Encoder) error { return enc.EncodeWithoutIncluded(testBlog()) },
+		"EncodeEmbedded":        func(enc *Encoder) error { return enc.EncodeEmbedded(testBlog()) },
+		"EncodeErrors": func(enc *Encoder) error {
+			return enc.EncodeErrors([]*ErrorObject{{Title: "Bad"}})
+		},
+		"Close": func(enc *Encoder) error {
+			if err := enc.WriteData(testBlog()); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+		"indented": func(enc *Encoder) error {
+			enc.SetIndent("", "  ")
+			return enc.Encode(testBlog())
+		},
+		"with codec": func(enc *Encoder) error {
+			enc.opts.codec = new(countingCodec)
+			return enc.Encode(testBlog())
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			with := bytes.NewBuffer(nil)
+			if err := encode(NewEncoder(with)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(with.Bytes(), []byte("}\n")) {
+				t.Fatalf("expected a trailing newline by default, got %q", with)
+			}
+
+			without := bytes.NewBuffer(nil)
+			enc := NewEncoder(without)
+			enc.SetTrailingNewline(false)
+			if err := encode(enc); err != nil {
+				t.Fatal(err)
+			}
+			if e, a := bytes.TrimSuffix(with.Bytes(), []byte("\n")), without.Bytes(); !bytes.Equal(e, a) {
+				t.Fatalf("expected %q, got %q", e, a)
+			}
+		})
+	}
+}
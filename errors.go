@@ -15,9 +15,9 @@
 package jsonapi
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // MarshalErrors writes a JSON API response using the given `[]error`.
@@ -25,8 +25,8 @@ import (
 // For more information on JSON API error payloads, see the spec here:
 // http://jsonapi.org/format/#document-top-level
 // and here: http://jsonapi.org/format/#error-objects.
-func MarshalErrors(w io.Writer, errorObjects []*ErrorObject) error {
-	return json.NewEncoder(w).Encode(&ErrorsPayload{Errors: errorObjects})
+func MarshalErrors(w io.Writer, errorObjects []*ErrorObject, opts ...Option) error {
+	return NewEncoder(w, opts...).EncodeErrors(errorObjects)
 }
 
 // ErrorsPayload is a serializer struct for representing a valid JSON API errors payload.
@@ -79,3 +79,109 @@ type Source struct {
 	// Header is a string indicating the name of a single request header which caused the error.
 	Header string `json:"header,omitempty"`
 }
+
+// errorObjecter is implemented by errors that know how to describe themselves
+// as a JSON API error object.
+type errorObjecter interface {
+	ErrorObject() *ErrorObject
+}
+
+// FieldError is returned when a single member of a resource object could not
+// be unmarshaled. It records the JSON Pointer [RFC6901] of the member in the
+// request document so that it can be reported back to the client.
+type FieldError struct {
+	// Pointer is a JSON Pointer to the offending member, e.g. "/data/attributes/title".
+	Pointer string
+
+	// Field is the name of the struct field the member was unmarshaled into.
+	Field string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func newFieldError(pointer, field string, err error) *FieldError {
+	return &FieldError{Pointer: pointer, Field: field, Err: err}
+}
+
+// Error implements the `Error` interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("jsonapi: %s: %v", e.Pointer, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorObject converts the error into an ErrorObject whose source points at
+// the offending member.
+func (e *FieldError) ErrorObject() *ErrorObject {
+	var obj ErrorObject
+	if eo, ok := e.Err.(errorObjecter); ok {
+		obj = *eo.ErrorObject()
+	} else {
+		obj = ErrorObject{
+			Title:  "Invalid Member",
+			Detail: e.Err.Error(),
+			Status: "400",
+		}
+	}
+	if e.Pointer != "" {
+		obj.Source = &Source{Pointer: e.Pointer}
+	}
+
+	return &obj
+}
+
+// MultiError is a list of errors that occurred while unmarshaling a single
+// payload. It is returned when the CollectErrors option is given and at least
+// one error occurred. MultiError supports errors.Is and errors.As by
+// unwrapping to its entries.
+type MultiError []error
+
+// Error implements the `Error` interface.
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the collected errors.
+func (m MultiError) Unwrap() []error {
+	return m
+}
+
+// ErrorObjects converts every collected error into an ErrorObject, ready to be
+// passed to MarshalErrors.
+func (m MultiError) ErrorObjects() []*ErrorObject {
+	objs := make([]*ErrorObject, 0, len(m))
+	for _, err := range m {
+		switch e := err.(type) {
+		case *ErrorObject:
+			objs = append(objs, e)
+		case errorObjecter:
+			objs = append(objs, e.ErrorObject())
+		default:
+			objs = append(objs, &ErrorObject{
+				Title:  "Bad Request",
+				Detail: err.Error(),
+				Status: "400",
+			})
+		}
+	}
+
+	return objs
+}
+
+// appendErrors appends err to errs, flattening a MultiError into its entries.
+func appendErrors(errs []error, err error) []error {
+	if m, ok := err.(MultiError); ok {
+		return append(errs, m...)
+	}
+
+	return append(errs, err)
+}
@@ -17,6 +17,7 @@ package jsonapi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -69,3 +70,40 @@ func TestMarshalErrorsWritesTheExpectedPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiErrorConvertsToErrorObjects(t *testing.T) {
+	merr := MultiError{
+		&ErrorObject{Title: "Custom", Status: "409"},
+		newFieldError("/data/attributes/title", "Title", ErrInvalidType),
+		errors.New("plain"),
+	}
+
+	objs := merr.ErrorObjects()
+	if e, a := 3, len(objs); e != a {
+		t.Fatalf("Was expecting %d error objects, got %d", e, a)
+	}
+	if objs[0] != merr[0] {
+		t.Fatal("Was expecting *ErrorObject entries to be passed through")
+	}
+	if objs[1].Source == nil || objs[1].Source.Pointer != "/data/attributes/title" {
+		t.Fatalf("Was expecting a source pointer, got %#v", objs[1].Source)
+	}
+	if e, a := ErrInvalidType.Error(), objs[1].Detail; e != a {
+		t.Fatalf("Was expecting detail %q, got %q", e, a)
+	}
+	if e, a := "plain", objs[2].Detail; e != a {
+		t.Fatalf("Was expecting detail %q, got %q", e, a)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalErrors(buf, objs); err != nil {
+		t.Fatal(err)
+	}
+	payload := new(ErrorsPayload)
+	if err := json.Unmarshal(buf.Bytes(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 3, len(payload.Errors); e != a {
+		t.Fatalf("Was expecting %d errors in the payload, got %d", e, a)
+	}
+}
@@ -1 +1,3 @@
-module github.com/companyinfo/jsonapi
\ No newline at end of file
+module github.com/companyinfo/jsonapi
+
+go 1.22
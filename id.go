@@ -0,0 +1,210 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// IDMarshaler is implemented by the type of a primary field that formats
+// its own resource id, e.g. a UUID or a composite key. The id is used for the
+// resource object, for the resource linkage of relationships to it and to
+// identify it among the included resources.
+//
+// Without it, a primary field implementing encoding.TextMarshaler is
+// formatted as text.
+type IDMarshaler interface {
+	MarshalJSONAPIID() (string, error)
+}
+
+// IDUnmarshaler is implemented by the type of a primary field that parses
+// its own resource id; it is the counterpart of IDMarshaler.
+//
+// Without it, a primary field implementing encoding.TextUnmarshaler is
+// parsed as text.
+type IDUnmarshaler interface {
+	UnmarshalJSONAPIID(id string) error
+}
+
+var idMarshalerType = reflect.TypeOf((*IDMarshaler)(nil)).Elem()
+
+// formatID returns the resource id held by v, the value of a primary field.
+func formatID(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		// The id of a new resource is left out
+		return "", nil
+	}
+
+	var id string
+	var err error
+
+	switch m := idMethods(v).(type) {
+	case IDMarshaler:
+		id, err = m.MarshalJSONAPIID()
+	case encoding.TextMarshaler:
+		var b []byte
+		b, err = m.MarshalText()
+		id = string(b)
+	default:
+		return formatIDValue(v)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrBadJSONAPIID, err)
+	}
+
+	return id, nil
+}
+
+// formatIDValue returns the resource id of v, a string or an integer.
+func formatIDValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		// We had a JSON float (numeric), but our field was not one of the
+		// allowed numeric types
+		return "", ErrBadJSONAPIID
+	}
+}
+
+// isIDType reports whether a primary field of type t, or a pointer to t, can
+// hold a resource id.
+func isIDType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	pt := reflect.PointerTo(t)
+	if pt.Implements(idMarshalerType) || pt.Implements(textMarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// idMethods returns the value of v, a primary field, holding the methods of
+// both its value and pointer receivers, or nil for a nil pointer.
+func idMethods(v reflect.Value) interface{} {
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	case v.CanAddr():
+		return v.Addr().Interface()
+	}
+
+	return v.Interface()
+}
+
+// parseID sets fieldValue, a primary field, to the resource id if its type
+// implements IDUnmarshaler or encoding.TextUnmarshaler, and reports whether
+// it does.
+func parseID(fieldValue reflect.Value, id string) (bool, error) {
+	t := fieldValue.Type()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+
+	v := reflect.New(t)
+
+	var err error
+	switch u := v.Interface().(type) {
+	case IDUnmarshaler:
+		err = u.UnmarshalJSONAPIID(id)
+	case encoding.TextUnmarshaler:
+		err = u.UnmarshalText([]byte(id))
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("%w: %w", ErrBadJSONAPIID, err)
+	}
+
+	if isPtr {
+		fieldValue.Set(v)
+	} else {
+		fieldValue.Set(v.Elem())
+	}
+
+	return true, nil
+}
+
+// setID sets fieldValue, a primary field or an element of an identifier-only
+// relation, to the resource id.
+func setID(fieldValue reflect.Value, id string) error {
+	// Types implementing IDUnmarshaler or encoding.TextUnmarshaler parse the
+	// id themselves
+	if ok, err := parseID(fieldValue, id); ok {
+		return err
+	}
+
+	// ID will have to be transmitted as astring per the JSON API spec
+	v := reflect.ValueOf(id)
+
+	// Deal with PTRS
+	fieldType := fieldValue.Type()
+	kind := fieldType.Kind()
+	if kind == reflect.Ptr {
+		kind = fieldType.Elem().Kind()
+	}
+
+	// Handle String case
+	if kind == reflect.String {
+		assign(fieldValue, v)
+		return nil
+	}
+
+	// Value was not a string... only other supported type was a numeric,
+	// which would have been sent as a float value.
+	floatValue, err := strconv.ParseFloat(id, 64)
+	if err != nil {
+		// Could not convert the value in the "id" attr to a float
+		return ErrBadJSONAPIID
+	}
+
+	// Convert the numeric float to one of the supported ID numeric types
+	// (int[8,16,32,64] or uint[8,16,32,64])
+	idValue, err := handleNumeric(floatValue, fieldType, fieldValue)
+	if err != nil {
+		// We had a JSON float (numeric), but our field was not one of the
+		// allowed numeric types
+		return ErrBadJSONAPIID
+	}
+
+	assign(fieldValue, idValue)
+
+	return nil
+}
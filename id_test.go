@@ -0,0 +1,119 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testOrder() *Order {
+	customer := &Customer{ID: &UUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x55, 0x44, 0, 0}, Name: "Jane"}
+
+	return &Order{
+		ID:        OrderKey{Shop: "acme", Number: 12},
+		Total:     100,
+		Customer:  customer,
+		Customers: []*Customer{customer, customer},
+	}
+}
+
+func TestCustomIDs_marshal(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testOrder()); err != nil {
+		t.Fatal(err)
+	}
+
+	const customerID = "123e4567-e89b-12d3-a456-426655440000"
+	expected := `{"data":{"type":"orders","id":"acme:12","attributes":{"total":100},"relationships":{` +
+		`"customer":{"data":{"type":"customers","id":"` + customerID + `"}},` +
+		`"customers":{"data":[{"type":"customers","id":"` + customerID + `"},{"type":"customers","id":"` + customerID + `"}]}}},` +
+		`"included":[{"type":"customers","id":"` + customerID + `","attributes":{"name":"Jane"}}]}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	nodeTree := bytes.NewBuffer(nil)
+	if err := marshalNodeTree(nodeTree, testOrder()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), nodeTree.Bytes()) {
+		t.Fatalf("output differs from the Node tree:\n%s\n%s", out, nodeTree)
+	}
+}
+
+func TestCustomIDs_nilID(t *testing.T) {
+	for _, model := range []interface{}{&Customer{Name: "Jane"}, &Car{}} {
+		out := bytes.NewBuffer(nil)
+		if err := MarshalPayload(out, model); err != nil {
+			t.Fatalf("Unexpected error marshaling %T: %v", model, err)
+		}
+		if strings.Contains(out.String(), `"id"`) {
+			t.Fatalf("Was expecting the nil id of %T to be left out, got %s", model, out)
+		}
+
+		nodeTree := bytes.NewBuffer(nil)
+		if err := marshalNodeTree(nodeTree, model); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), nodeTree.Bytes()) {
+			t.Fatalf("output differs from the Node tree:\n%s\n%s", out, nodeTree)
+		}
+	}
+}
+
+func TestCustomIDs_roundTrip(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testOrder()); err != nil {
+		t.Fatal(err)
+	}
+
+	order := new(Order)
+	if err := UnmarshalPayload(out, order); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, testOrder()) {
+		t.Fatalf("Was expecting %+v, got %+v", testOrder(), order)
+	}
+}
+
+func TestCustomIDs_invalid(t *testing.T) {
+	doc := `{"data": {"type": "orders", "id": "acme", "relationships": {
+		"customer": {"data": {"type": "customers", "id": "not-a-uuid"}}
+	}}}`
+
+	err := UnmarshalPayload(strings.NewReader(doc), new(Order), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) || len(merr) != 2 {
+		t.Fatalf("Was expecting two errors, got %v", err)
+	}
+	for _, e := range merr {
+		if !errors.Is(e, ErrBadJSONAPIID) {
+			t.Fatalf("Was expecting ErrBadJSONAPIID, got %v", e)
+		}
+	}
+	if !strings.Contains(merr[0].Error(), `invalid order key "acme"`) {
+		t.Fatalf("Was expecting the error of UnmarshalJSONAPIID, got %v", merr[0])
+	}
+
+	err = MarshalPayload(bytes.NewBuffer(nil), &Order{})
+	if !errors.Is(err, ErrBadJSONAPIID) || !strings.Contains(err.Error(), "missing shop") {
+		t.Fatalf("Was expecting the error of MarshalJSONAPIID, got %v", err)
+	}
+}
@@ -15,7 +15,11 @@
 package jsonapi
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -209,3 +213,180 @@ type CustomAttributeTypes struct {
 	Float  CustomFloatType  `jsonapi:"attr,float"`
 	String CustomStringType `jsonapi:"attr,string"`
 }
+
+type Account struct {
+	ID        int       `jsonapi:"primary,accounts"`
+	Name      string    `jsonapi:"attr,name"`
+	Email     string    `jsonapi:"attr,email,createonly"`
+	Password  string    `jsonapi:"attr,password,writeonly"`
+	CreatedAt time.Time `jsonapi:"attr,created_at,readonly,iso8601"`
+	Manager   *Account  `jsonapi:"relation,manager,readonly,omitempty"`
+}
+
+type Labels map[CustomStringType]string
+
+type MapAttributes struct {
+	ID     string                    `jsonapi:"primary,maps"`
+	Counts map[string]int            `jsonapi:"attr,counts"`
+	Scores map[int]float64           `jsonapi:"attr,scores"`
+	Labels Labels                    `jsonapi:"attr,labels"`
+	Times  map[string]*time.Time     `jsonapi:"attr,times,iso8601"`
+	Nested map[string]map[string]int `jsonapi:"attr,nested"`
+	Extra  *map[string]string        `jsonapi:"attr,extra,omitempty"`
+	Any    map[string]interface{}    `jsonapi:"attr,any"`
+}
+
+type Scores []float64
+
+type SliceAttributes struct {
+	ID      string           `jsonapi:"primary,slices"`
+	Ints    []int            `jsonapi:"attr,ints"`
+	Scores  Scores           `jsonapi:"attr,scores"`
+	Names   []*string        `jsonapi:"attr,names"`
+	Times   []time.Time      `jsonapi:"attr,times,rfc3339"`
+	Matrix  [][]string       `jsonapi:"attr,matrix"`
+	Point   [3]int           `jsonapi:"attr,point"`
+	Data    []byte           `jsonapi:"attr,data"`
+	Members []*Employee      `jsonapi:"attr,members"`
+	Buckets []map[string]int `jsonapi:"attr,buckets"`
+	Ptr     *[]uint8         `jsonapi:"attr,ptr,omitempty"`
+	Any     []interface{}    `jsonapi:"attr,any"`
+}
+
+type Address struct {
+	Street string `json:"street"`
+	City   string `json:"city,omitempty"`
+}
+
+type Office struct {
+	ID      string              `jsonapi:"primary,offices"`
+	Address Address             `jsonapi:"attr,address"`
+	Manager *Employee           `jsonapi:"attr,manager,omitempty"`
+	Staff   map[string]Employee `jsonapi:"attr,staff"`
+}
+
+type TimeFormats struct {
+	ID     string      `jsonapi:"primary,time-formats"`
+	Nano   time.Time   `jsonapi:"attr,nano,rfc3339nano"`
+	Milli  time.Time   `jsonapi:"attr,milli,unixmilli"`
+	Micro  *time.Time  `jsonapi:"attr,micro,unixmicro"`
+	Date   time.Time   `jsonapi:"attr,date,date"`
+	Zoned  time.Time   `jsonapi:"attr,zoned,rfc3339,keepzone"`
+	Custom time.Time   `jsonapi:"attr,custom,rfc1123"`
+	Dates  []time.Time `jsonapi:"attr,dates,omitempty,date"`
+}
+
+type Date time.Time
+
+type Schedule struct {
+	ID      string          `jsonapi:"primary,schedules"`
+	Timeout time.Duration   `jsonapi:"attr,timeout"`
+	TTL     time.Duration   `jsonapi:"attr,ttl,seconds"`
+	Period  *time.Duration  `jsonapi:"attr,period,iso8601,omitempty"`
+	Steps   []time.Duration `jsonapi:"attr,steps,iso8601"`
+	Start   Date            `jsonapi:"attr,start,date"`
+	End     *Date           `jsonapi:"attr,end,date,omitempty"`
+}
+
+type Nullables struct {
+	ID      string           `jsonapi:"primary,nullables"`
+	Name    sql.NullString   `jsonapi:"attr,name"`
+	Count   sql.NullInt64    `jsonapi:"attr,count"`
+	Score   *sql.NullFloat64 `jsonapi:"attr,score,omitempty"`
+	Deleted sql.NullTime     `jsonapi:"attr,deleted,rfc3339"`
+	Flags   []sql.NullBool   `jsonapi:"attr,flags"`
+	Code    sql.Null[string] `jsonapi:"attr,code,omitempty"`
+}
+
+type UUID [16]byte
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	var parts [5][]byte
+	n, err := fmt.Sscanf(string(text), "%8x-%4x-%4x-%4x-%12x",
+		&parts[0], &parts[1], &parts[2], &parts[3], &parts[4])
+	if err != nil || n != 5 || len(text) != 36 {
+		return fmt.Errorf("invalid UUID %q", text)
+	}
+
+	b := u[:0]
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+
+	return nil
+}
+
+type OrderKey struct {
+	Shop   string
+	Number int
+}
+
+func (k OrderKey) MarshalJSONAPIID() (string, error) {
+	if k.Shop == "" {
+		return "", errors.New("missing shop")
+	}
+
+	return fmt.Sprintf("%s:%d", k.Shop, k.Number), nil
+}
+
+func (k *OrderKey) UnmarshalJSONAPIID(id string) error {
+	i := strings.LastIndexByte(id, ':')
+	if i < 0 {
+		return fmt.Errorf("invalid order key %q", id)
+	}
+
+	number, err := strconv.Atoi(id[i+1:])
+	if err != nil {
+		return err
+	}
+	k.Shop, k.Number = id[:i], number
+
+	return nil
+}
+
+type Order struct {
+	ID        OrderKey    `jsonapi:"primary,orders"`
+	Total     int         `jsonapi:"attr,total"`
+	Customer  *Customer   `jsonapi:"relation,customer"`
+	Customers []*Customer `jsonapi:"relation,customers"`
+}
+
+type Customer struct {
+	ID   *UUID  `jsonapi:"primary,customers"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type Author struct {
+	ID   int    `jsonapi:"primary,authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type Authors []Author
+
+type Article struct {
+	ID         int       `jsonapi:"primary,articles"`
+	Title      string    `jsonapi:"attr,title"`
+	Lead       Author    `jsonapi:"relation,lead"`
+	Editor     Author    `jsonapi:"relation,editor,omitempty"`
+	Translator Author    `jsonapi:"relation,translator"`
+	Authors    Authors   `jsonapi:"relation,authors"`
+	Reviewers  []*Author `jsonapi:"relation,reviewers,omitempty"`
+}
+
+type Note struct {
+	ID       int      `jsonapi:"primary,notes"`
+	Body     string   `jsonapi:"attr,body"`
+	AuthorID int      `jsonapi:"relation,author,type=people"`
+	EditorID *string  `jsonapi:"relation,editor,type=people,omitempty"`
+	TagIDs   []string `jsonapi:"relation,tags,type=tags"`
+	ShopID   *UUID    `jsonapi:"relation,shop,type=shops"`
+}
+
+type Person struct {
+	ID   string `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
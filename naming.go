@@ -0,0 +1,94 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy derives the member name of an attribute or relation from the
+// name of its Go struct field when the jsonapi tag omits it, as in
+// `jsonapi:"attr"` or `jsonapi:"attr,,omitempty"`.
+type NamingStrategy int
+
+const (
+	// NoNamingStrategy requires every attribute and relation tag to name its
+	// member explicitly. It is the default.
+	NoNamingStrategy NamingStrategy = iota
+	// CamelCase derives names such as "createdAt" from CreatedAt.
+	CamelCase
+	// KebabCase derives names such as "created-at" from CreatedAt.
+	KebabCase
+	// SnakeCase derives names such as "created_at" from CreatedAt.
+	SnakeCase
+)
+
+// WithNamingStrategy sets the strategy used to derive the member names of
+// attributes and relations whose jsonapi tag omits them. The same strategy
+// must be used to marshal and unmarshal a model.
+func WithNamingStrategy(s NamingStrategy) Option {
+	return func(o *options) {
+		o.naming = s
+	}
+}
+
+// memberName derives a member name from fieldName, or returns "" if s does
+// not derive names.
+func (s NamingStrategy) memberName(fieldName string) string {
+	words := splitWords(fieldName)
+
+	switch s {
+	case CamelCase:
+		for i, w := range words {
+			if i == 0 {
+				continue
+			}
+			r := []rune(w)
+			words[i] = string(unicode.ToUpper(r[0])) + string(r[1:])
+		}
+		return strings.Join(words, "")
+	case KebabCase:
+		return strings.Join(words, "-")
+	case SnakeCase:
+		return strings.Join(words, "_")
+	default:
+		return ""
+	}
+}
+
+// splitWords splits a Go identifier into lower case words, treating runs of
+// upper case letters as acronyms: "HTTPServerID" becomes "http", "server",
+// "id". Digits stay attached to the word they follow.
+func splitWords(name string) []string {
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			lowerNext := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsUpper(cur) && (!unicode.IsUpper(prev) || lowerNext) {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			words = append(words, strings.ToLower(string(runes[start:])))
+		}
+	}
+
+	return words
+}
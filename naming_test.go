@@ -0,0 +1,160 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNamingStrategy_memberName(t *testing.T) {
+	for _, tc := range []struct {
+		field               string
+		camel, kebab, snake string
+	}{
+		{"Title", "title", "title", "title"},
+		{"CreatedAt", "createdAt", "created-at", "created_at"},
+		{"AuthorID", "authorId", "author-id", "author_id"},
+		{"HTTPServerURL", "httpServerUrl", "http-server-url", "http_server_url"},
+		{"ISBN13", "isbn13", "isbn13", "isbn13"},
+		{"Version2Name", "version2Name", "version2-name", "version2_name"},
+		{"already_snake", "alreadySnake", "already-snake", "already_snake"},
+	} {
+		if e, a := tc.camel, CamelCase.memberName(tc.field); e != a {
+			t.Errorf("CamelCase(%s): was expecting %q, got %q", tc.field, e, a)
+		}
+		if e, a := tc.kebab, KebabCase.memberName(tc.field); e != a {
+			t.Errorf("KebabCase(%s): was expecting %q, got %q", tc.field, e, a)
+		}
+		if e, a := tc.snake, SnakeCase.memberName(tc.field); e != a {
+			t.Errorf("SnakeCase(%s): was expecting %q, got %q", tc.field, e, a)
+		}
+	}
+
+	if name := NoNamingStrategy.memberName("Title"); name != "" {
+		t.Errorf("Was expecting NoNamingStrategy not to derive names, got %q", name)
+	}
+}
+
+type NamedArticle struct {
+	ID           string   `jsonapi:"primary,articles"`
+	Title        string   `jsonapi:"attr"`
+	ViewCount    int      `jsonapi:"attr,,omitempty"`
+	LegacyField  string   `jsonapi:"attr,legacy"`
+	LeadAuthor   *Comment `jsonapi:"relation"`
+	RelatedPosts []*Post  `jsonapi:"relation,,omitempty"`
+	PublishedAt  time.Time
+}
+
+func TestNamingStrategy_marshalAndUnmarshal(t *testing.T) {
+	in := &NamedArticle{
+		ID:          "1",
+		Title:       "Hello",
+		ViewCount:   3,
+		LegacyField: "kept",
+		LeadAuthor:  &Comment{ID: 2, Body: "author"},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, in, WithNamingStrategy(KebabCase)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Data struct {
+			Attributes    map[string]interface{} `json:"attributes"`
+			Relationships map[string]interface{} `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var attrs, rels []string
+	for k := range doc.Data.Attributes {
+		attrs = append(attrs, k)
+	}
+	for k := range doc.Data.Relationships {
+		rels = append(rels, k)
+	}
+	sort.Strings(attrs)
+	if e := []string{"legacy", "title", "view-count"}; !reflect.DeepEqual(e, attrs) {
+		t.Fatalf("Was expecting attributes %v, got %v", e, attrs)
+	}
+	if e := []string{"lead-author"}; !reflect.DeepEqual(e, rels) {
+		t.Fatalf("Was expecting relationships %v, got %v", e, rels)
+	}
+
+	decoded := new(NamedArticle)
+	if err := UnmarshalPayload(bytes.NewReader(out.Bytes()), decoded, WithNamingStrategy(KebabCase)); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != in.Title || decoded.ViewCount != in.ViewCount || decoded.LegacyField != in.LegacyField {
+		t.Fatalf("Was expecting %+v, got %+v", in, decoded)
+	}
+	if decoded.LeadAuthor == nil || decoded.LeadAuthor.Body != "author" {
+		t.Fatalf("Was expecting the lead author to be unmarshaled, got %+v", decoded.LeadAuthor)
+	}
+}
+
+func TestNamingStrategy_required(t *testing.T) {
+	err := MarshalPayload(bytes.NewBuffer(nil), &NamedArticle{ID: "1"})
+	if err != ErrBadJSONAPIStructTag {
+		t.Fatalf("Was expecting %v without a naming strategy, got %v", ErrBadJSONAPIStructTag, err)
+	}
+}
+
+func TestInvalidMemberNames(t *testing.T) {
+	type badAttr struct {
+		ID    string `jsonapi:"primary,bad-attrs"`
+		Title string `jsonapi:"attr,the.title"`
+	}
+	type reservedAttr struct {
+		ID   string `jsonapi:"primary,reserved-attrs"`
+		Type string `jsonapi:"attr,type"`
+	}
+	type badRelation struct {
+		ID     string   `jsonapi:"primary,bad-relations"`
+		Author *Comment `jsonapi:"relation,_author"`
+	}
+	type badType struct {
+		ID string `jsonapi:"primary,bad types!"`
+	}
+
+	for _, model := range []interface{}{
+		&badAttr{ID: "1"},
+		&reservedAttr{ID: "1"},
+		&badRelation{ID: "1"},
+		&badType{ID: "1"},
+	} {
+		t.Run(reflect.TypeOf(model).Elem().Name(), func(t *testing.T) {
+			err := MarshalPayload(bytes.NewBuffer(nil), model)
+			var terr *TagError
+			if !errors.As(err, &terr) || !errors.Is(err, ErrInvalidMemberName) {
+				t.Fatalf("Was expecting a *TagError wrapping ErrInvalidMemberName on marshal, got %v", err)
+			}
+
+			err = UnmarshalPayload(bytes.NewReader([]byte(`{"data":{"type":"x"}}`)), model)
+			if !errors.Is(err, ErrInvalidMemberName) {
+				t.Fatalf("Was expecting ErrInvalidMemberName on unmarshal, got %v", err)
+			}
+		})
+	}
+}
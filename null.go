@@ -0,0 +1,74 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// nullValueIndex returns the index of the value field of t if t is one of the
+// nullable types of database/sql, such as sql.NullString, sql.NullTime or
+// sql.Null[T]. Their Valid field reports whether the value is not NULL, which
+// is an attribute value of null.
+func nullValueIndex(t reflect.Type) (int, bool) {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "database/sql" ||
+		!strings.HasPrefix(t.Name(), "Null") || t.NumField() != 2 {
+		return 0, false
+	}
+
+	valid, ok := t.FieldByName("Valid")
+	if !ok || valid.Type.Kind() != reflect.Bool {
+		return 0, false
+	}
+
+	return 1 - valid.Index[0], true
+}
+
+// nullValue returns the value of v, a nullable type of database/sql, and
+// whether it is valid.
+func nullValue(v reflect.Value, index int) (reflect.Value, bool) {
+	return v.Field(index), v.Field(1 - index).Bool()
+}
+
+// handleNull unmarshals the attribute value of t, a nullable type of
+// database/sql or a pointer to one. Its Valid field is set, as the attribute
+// is not null.
+func (ctx *unmarshalContext) handleNull(
+	attribute interface{},
+	args []string,
+	t reflect.Type,
+	name string) (reflect.Value, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	index, _ := nullValueIndex(t)
+
+	value, err := ctx.unmarshalElement(attribute, args, t.Field(index).Type, name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	null := reflect.New(t)
+	null.Elem().Field(index).Set(value)
+	null.Elem().Field(1 - index).SetBool(true)
+
+	if isPtr {
+		return null, nil
+	}
+
+	return null.Elem(), nil
+}
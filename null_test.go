@@ -0,0 +1,86 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNullables_marshal(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	err := MarshalPayload(out, &Nullables{
+		ID:      "1",
+		Name:    sql.NullString{String: "Jane", Valid: true},
+		Count:   sql.NullInt64{Int64: 3},
+		Deleted: sql.NullTime{Time: time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC), Valid: true},
+		Flags:   []sql.NullBool{{Bool: true, Valid: true}, {}},
+		Code:    sql.Null[string]{V: "A1", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"data":{"type":"nullables","id":"1","attributes":{` +
+		`"code":"A1",` +
+		`"count":null,` +
+		`"deleted":"2016-08-17T08:27:12Z",` +
+		`"flags":[true,null],` +
+		`"name":"Jane"}}}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+}
+
+func TestNullables_unmarshal(t *testing.T) {
+	doc := `{"data": {"type": "nullables", "id": "1", "attributes": {
+		"name": "Jane",
+		"count": null,
+		"score": 4.5,
+		"deleted": "2016-08-17T08:27:12Z",
+		"flags": [true, null],
+		"code": "A1"
+	}}}`
+
+	model := new(Nullables)
+	if err := UnmarshalPayload(strings.NewReader(doc), model); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Nullables{
+		ID:      "1",
+		Name:    sql.NullString{String: "Jane", Valid: true},
+		Score:   &sql.NullFloat64{Float64: 4.5, Valid: true},
+		Deleted: sql.NullTime{Time: time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC), Valid: true},
+		Flags:   []sql.NullBool{{Bool: true, Valid: true}, {}},
+		Code:    sql.Null[string]{V: "A1", Valid: true},
+	}
+	if !reflect.DeepEqual(model, expected) {
+		t.Fatalf("Was expecting %+v, got %+v", expected, model)
+	}
+}
+
+func TestNullables_invalidValue(t *testing.T) {
+	doc := `{"data": {"type": "nullables", "attributes": {"deleted": 1471422432}}}`
+
+	err := UnmarshalPayload(strings.NewReader(doc), new(Nullables))
+	if err != ErrInvalidRFC3339 {
+		t.Fatalf("Was expecting ErrInvalidRFC3339, got %v", err)
+	}
+}
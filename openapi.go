@@ -0,0 +1,360 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+
+	// componentSchemas is the prefix of references to component schemas.
+	componentSchemas = "#/components/schemas/"
+)
+
+// ErrConflictingSchema is returned by OpenAPI when two models generate
+// different definitions under the same component schema name, e.g. two
+// struct types with the same resource type, or a related model described
+// with a naming strategy other than the one it was registered with.
+var ErrConflictingSchema = errors.New("jsonapi: conflicting schema definitions")
+
+// openAPIObject is an object of an OpenAPI document.
+type openAPIObject = map[string]interface{}
+
+// OpenAPI generates an OpenAPI 3.1 document, ready to be encoded with
+// encoding/json, describing the endpoints of the registered resource types
+// at the paths recommended by JSON:API:
+//
+//	/{type}                                    list and create resources
+//	/{type}/{id}                               fetch, update and delete a resource
+//	/{type}/{id}/{relationship}                fetch related resources
+//	/{type}/{id}/relationships/{relationship}  fetch and update linkage
+//
+// Resource objects are component schemas named as the definitions of
+// JSONSchemas. The include, fields, sort, filter and page query parameters
+// are component parameters, and every operation returns errors with the
+// "error" component response, a document of the ErrorsPayload shape. Related
+// endpoints of identifier-only relations are described when their resource
+// type is registered. Fields such as "servers" may be added to the document
+// before it is published. Update requests are described by the
+// "{type}-update-request" schemas, which require the resource id.
+//
+// An error wrapping ErrConflictingSchema is returned if two definitions of a
+// component schema differ.
+func (r *Registry) OpenAPI(title, version string) (map[string]interface{}, error) {
+	g := &openAPIGenerator{
+		registry: r,
+		schemas:  Schema{},
+		paths:    openAPIObject{},
+	}
+	for _, rt := range r.ResourceTypes() {
+		g.resourcePaths(rt)
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	return openAPIObject{
+		"openapi": openAPIVersion,
+		"info": openAPIObject{
+			"title":   title,
+			"version": version,
+		},
+		"paths": g.paths,
+		"components": openAPIObject{
+			"schemas":    g.schemas,
+			"parameters": openAPIParameters(),
+			"responses": openAPIObject{
+				"error": documentResponse("Error", errorsSchema()),
+			},
+		},
+	}, nil
+}
+
+// openAPIGenerator collects the paths and component schemas of an OpenAPI
+// document.
+type openAPIGenerator struct {
+	registry *Registry
+	schemas  Schema
+	paths    openAPIObject
+
+	// err is the first conflict between definitions.
+	err error
+}
+
+// define adds the definitions of g to the component schemas, recording a
+// conflict if a definition of the same name differs.
+func (o *openAPIGenerator) define(g *schemaGenerator) {
+	names := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := g.defs[name]
+		existing, ok := o.schemas[name]
+		if !ok {
+			o.schemas[name] = s
+			continue
+		}
+		if o.err == nil && !reflect.DeepEqual(existing, s) {
+			o.err = fmt.Errorf("%w: %q", ErrConflictingSchema, name)
+		}
+	}
+}
+
+// resourcePaths adds the paths of the registered resource type rt.
+func (o *openAPIGenerator) resourcePaths(rt *ResourceType) {
+	g := newSchemaGenerator(rt.naming, componentSchemas)
+	request := Schema{"$ref": g.resourceRef(rt.Type, kindRequest)}
+	update := Schema{"$ref": g.resourceRef(rt.Type, kindUpdate)}
+	response := Schema{"$ref": g.resourceRef(rt.Type, kindResponse)}
+	included := g.includedSchema()
+
+	collection := "/" + rt.Name
+	individual := collection + "/{id}"
+	tags := []string{rt.Name}
+	of := " of " + rt.Name
+
+	o.paths[collection] = openAPIObject{
+		"get": openAPIOperation(tags, "list-"+rt.Name, "List "+rt.Name,
+			[]string{"include", "fields", "sort", "filter", "page"}, nil, openAPIObject{
+				"200": documentResponse("The "+rt.Name, documentSchema(Schema{"type": "array", "items": response}, included)),
+			}),
+		"post": openAPIOperation(tags, "create-"+rt.Name, "Create a resource"+of,
+			nil, documentSchema(request, nil), openAPIObject{
+				"201": documentResponse("The created resource", documentSchema(response, included)),
+			}),
+	}
+	o.paths[individual] = openAPIObject{
+		"parameters": []interface{}{parameterRef("id")},
+		"get": openAPIOperation(tags, "get-"+rt.Name, "Fetch a resource"+of,
+			[]string{"include", "fields"}, nil, openAPIObject{
+				"200": documentResponse("The resource", documentSchema(response, included)),
+			}),
+		"patch": openAPIOperation(tags, "update-"+rt.Name, "Update a resource"+of,
+			nil, documentSchema(update, nil), openAPIObject{
+				"200": documentResponse("The updated resource", documentSchema(response, included)),
+			}),
+		"delete": openAPIOperation(tags, "delete-"+rt.Name, "Delete a resource"+of,
+			nil, nil, openAPIObject{
+				"204": openAPIObject{"description": "The resource was deleted"},
+			}),
+	}
+
+	info, _ := getModelInfo(rt.Type, rt.naming)
+	for _, field := range info.fields {
+		if field.annotation != annotationRelation {
+			continue
+		}
+		t := rt.Type.Field(field.index).Type
+		toMany := t.Kind() == reflect.Slice
+		id := rt.Name + "-" + field.name
+
+		if path, ok := o.relatedPath(t, field, rt.naming, tags, id, toMany); ok {
+			o.paths[individual+"/"+field.name] = path
+		}
+
+		linkage := g.relationshipSchema(t, field, true)
+		path := openAPIObject{
+			"parameters": []interface{}{parameterRef("id")},
+			"get": openAPIOperation(tags, "get-"+id+"-relationship", "Fetch the "+field.name+" linkage"+of,
+				nil, nil, openAPIObject{
+					"200": documentResponse("The linkage", g.relationshipSchema(t, field, false)),
+				}),
+			"patch": openAPIOperation(tags, "update-"+id+"-relationship", "Replace the "+field.name+of,
+				nil, linkage, openAPIObject{
+					"204": openAPIObject{"description": "The relationship was updated"},
+				}),
+		}
+		if toMany {
+			path["post"] = openAPIOperation(tags, "add-"+id+"-relationship", "Add to the "+field.name+of,
+				nil, linkage, openAPIObject{
+					"204": openAPIObject{"description": "The relationship was updated"},
+				})
+			path["delete"] = openAPIOperation(tags, "remove-"+id+"-relationship", "Remove from the "+field.name+of,
+				nil, linkage, openAPIObject{
+					"204": openAPIObject{"description": "The relationship was updated"},
+				})
+		}
+		o.paths[individual+"/relationships/"+field.name] = path
+	}
+
+	o.define(g)
+}
+
+// relatedPath returns the path item of the related resources of the
+// relation field of type t, and false if the model of an identifier-only
+// relation is not registered.
+func (o *openAPIGenerator) relatedPath(t reflect.Type, field *fieldInfo, naming NamingStrategy,
+	tags []string, id string, toMany bool) (openAPIObject, bool) {
+	related := relatedType(t)
+	if field.linkageType != "" {
+		rt, ok := o.registry.Lookup(field.linkageType)
+		if !ok {
+			return nil, false
+		}
+		related, naming = rt.Type, rt.naming
+	}
+
+	g := newSchemaGenerator(naming, componentSchemas)
+	resource := Schema{"$ref": g.resourceRef(related, kindResponse)}
+	included := g.includedSchema()
+	o.define(g)
+
+	params := []string{"include", "fields"}
+	data := Schema{"anyOf": []interface{}{resource, Schema{"type": "null"}}}
+	if toMany {
+		params = append(params, "sort", "filter", "page")
+		data = Schema{"type": "array", "items": resource}
+	}
+
+	return openAPIObject{
+		"parameters": []interface{}{parameterRef("id")},
+		"get": openAPIOperation(tags, "get-"+id, "Fetch the "+field.name+" of a resource",
+			params, nil, openAPIObject{
+				"200": documentResponse("The related resources", documentSchema(data, included)),
+			}),
+	}, true
+}
+
+// openAPIOperation returns an operation object with the query parameters
+// params, a request body of the schema body unless it is nil, and responses
+// along with the default error response.
+func openAPIOperation(tags []string, id, summary string, params []string, body Schema, responses openAPIObject) openAPIObject {
+	responses["default"] = openAPIObject{"$ref": "#/components/responses/error"}
+
+	op := openAPIObject{
+		"tags":        tags,
+		"operationId": id,
+		"summary":     summary,
+		"responses":   responses,
+	}
+	if len(params) > 0 {
+		refs := make([]interface{}, len(params))
+		for i, name := range params {
+			refs[i] = parameterRef(name)
+		}
+		op["parameters"] = refs
+	}
+	if body != nil {
+		op["requestBody"] = openAPIObject{
+			"required": true,
+			"content": openAPIObject{
+				MediaType: openAPIObject{"schema": body},
+			},
+		}
+	}
+
+	return op
+}
+
+func parameterRef(name string) openAPIObject {
+	return openAPIObject{"$ref": "#/components/parameters/" + name}
+}
+
+// documentResponse returns a response object holding a document of the
+// JSON:API media type.
+func documentResponse(description string, schema Schema) openAPIObject {
+	return openAPIObject{
+		"description": description,
+		"content": openAPIObject{
+			MediaType: openAPIObject{"schema": schema},
+		},
+	}
+}
+
+// openAPIParameters returns the component parameters: the query parameters
+// of JSON:API and the id of individual resources.
+func openAPIParameters() openAPIObject {
+	family := func(name, description string) openAPIObject {
+		return openAPIObject{
+			"name":        name,
+			"in":          "query",
+			"description": description,
+			"style":       "deepObject",
+			"explode":     true,
+			"schema": Schema{
+				"type":                 "object",
+				"additionalProperties": Schema{"type": "string"},
+			},
+		}
+	}
+
+	return openAPIObject{
+		"id": openAPIObject{
+			"name":     "id",
+			"in":       "path",
+			"required": true,
+			"schema":   Schema{"type": "string"},
+		},
+		"include": openAPIObject{
+			"name":        "include",
+			"in":          "query",
+			"description": "Comma-separated relationship paths of the related resources to include.",
+			"schema":      Schema{"type": "string"},
+		},
+		"fields": family("fields", "Sparse fieldsets: the comma-separated members to return for each resource type."),
+		"sort": openAPIObject{
+			"name":        "sort",
+			"in":          "query",
+			"description": "Comma-separated sort fields, descending when prefixed with a minus.",
+			"schema":      Schema{"type": "string"},
+		},
+		"filter": family("filter", "Filters of the resources."),
+		"page":   family("page", "Pagination of the resources."),
+	}
+}
+
+// errorsSchema returns the schema of an ErrorsPayload document.
+func errorsSchema() Schema {
+	str := Schema{"type": "string"}
+
+	return Schema{
+		"type":     "object",
+		"required": []string{"errors"},
+		"properties": Schema{
+			"errors": Schema{
+				"type": "array",
+				"items": Schema{
+					"type": "object",
+					"properties": Schema{
+						"id":     str,
+						"title":  str,
+						"detail": str,
+						"status": str,
+						"code":   str,
+						"source": Schema{
+							"type": "object",
+							"properties": Schema{
+								"pointer":   str,
+								"parameter": str,
+								"header":    str,
+							},
+						},
+						"meta": Schema{"type": "object"},
+					},
+				},
+			},
+			"meta":    Schema{"type": "object"},
+			"jsonapi": Schema{"type": "object"},
+		},
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// openAPIDocument returns the OpenAPI document of the models as decoded
+// JSON.
+func openAPIDocument(t *testing.T, models ...interface{}) map[string]interface{} {
+	t.Helper()
+
+	doc, err := newTestRegistry(t, models...).OpenAPI("Blogs", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+// jsonAt returns the value of the decoded JSON v at the path of member names.
+func jsonAt(t *testing.T, v interface{}, path ...string) interface{} {
+	t.Helper()
+
+	for _, name := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Was expecting an object at %s in %v", name, path)
+		}
+		v = m[name]
+	}
+
+	return v
+}
+
+// objectKeys returns the sorted member names of the decoded JSON object v.
+func objectKeys(t *testing.T, v interface{}) []string {
+	t.Helper()
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Was expecting an object, got %v", v)
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestOpenAPI_paths(t *testing.T) {
+	doc := openAPIDocument(t, new(Blog), new(Note), new(Person))
+
+	if doc["openapi"] != "3.1.0" || jsonAt(t, doc, "info", "title") != "Blogs" {
+		t.Fatalf("Unexpected document header %v, %v", doc["openapi"], doc["info"])
+	}
+
+	want := []string{
+		"/blogs",
+		"/blogs/{id}",
+		"/blogs/{id}/current_post",
+		"/blogs/{id}/posts",
+		"/blogs/{id}/relationships/current_post",
+		"/blogs/{id}/relationships/posts",
+		"/notes",
+		"/notes/{id}",
+		// Only the related resources of registered identifier-only
+		// relations are described
+		"/notes/{id}/author",
+		"/notes/{id}/editor",
+		"/notes/{id}/relationships/author",
+		"/notes/{id}/relationships/editor",
+		"/notes/{id}/relationships/shop",
+		"/notes/{id}/relationships/tags",
+		"/people",
+		"/people/{id}",
+	}
+	paths := jsonAt(t, doc, "paths")
+	if got := objectKeys(t, paths); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Was expecting paths %v, got %v", want, got)
+	}
+
+	for path, methods := range map[string][]string{
+		"/blogs":                                 {"get", "post"},
+		"/blogs/{id}":                            {"delete", "get", "parameters", "patch"},
+		"/blogs/{id}/posts":                      {"get", "parameters"},
+		"/blogs/{id}/relationships/posts":        {"delete", "get", "parameters", "patch", "post"},
+		"/blogs/{id}/relationships/current_post": {"get", "parameters", "patch"},
+	} {
+		if got := objectKeys(t, jsonAt(t, paths, path)); !reflect.DeepEqual(got, methods) {
+			t.Errorf("Was expecting %v for %s, got %v", methods, path, got)
+		}
+	}
+}
+
+func TestOpenAPI_operations(t *testing.T) {
+	doc := openAPIDocument(t, new(Blog))
+	blogs := jsonAt(t, doc, "paths", "/blogs")
+
+	list := jsonAt(t, blogs, "get")
+	if list.(map[string]interface{})["operationId"] != "list-blogs" {
+		t.Fatalf("Unexpected operation id %v", list)
+	}
+	assertJSON(t, jsonAt(t, list, "parameters"), `[
+		{"$ref": "#/components/parameters/include"},
+		{"$ref": "#/components/parameters/fields"},
+		{"$ref": "#/components/parameters/sort"},
+		{"$ref": "#/components/parameters/filter"},
+		{"$ref": "#/components/parameters/page"}
+	]`)
+
+	content := jsonAt(t, list, "responses", "200", "content", MediaType, "schema", "properties")
+	assertJSON(t, jsonAt(t, content, "data"), `{"type": "array", "items": {"$ref": "#/components/schemas/blogs"}}`)
+	assertJSON(t, jsonAt(t, content, "included", "items"), `{"oneOf": [
+		{"$ref": "#/components/schemas/comments"}, {"$ref": "#/components/schemas/posts"}]}`)
+	assertJSON(t, jsonAt(t, list, "responses", "default"), `{"$ref": "#/components/responses/error"}`)
+
+	create := jsonAt(t, blogs, "post")
+	assertJSON(t, jsonAt(t, create, "requestBody", "content", MediaType, "schema", "properties", "data"),
+		`{"$ref": "#/components/schemas/blogs-request"}`)
+	if jsonAt(t, create, "responses", "201") == nil {
+		t.Fatal("Was expecting a 201 response to create requests")
+	}
+
+	update := jsonAt(t, doc, "paths", "/blogs/{id}", "patch")
+	assertJSON(t, jsonAt(t, update, "requestBody", "content", MediaType, "schema", "properties", "data"),
+		`{"$ref": "#/components/schemas/blogs-update-request"}`)
+	assertJSON(t, jsonAt(t, doc, "components", "schemas", "blogs-update-request", "required"), `["type", "id"]`)
+	assertJSON(t, jsonAt(t, doc, "components", "schemas", "blogs-request", "required"), `["type"]`)
+
+	linkage := jsonAt(t, doc, "paths", "/blogs/{id}/relationships/posts", "patch",
+		"requestBody", "content", MediaType, "schema")
+	assertJSON(t, jsonAt(t, linkage, "properties", "data", "items", "properties", "type"), `{"const": "posts"}`)
+
+	related := jsonAt(t, doc, "paths", "/blogs/{id}/current_post", "get", "responses", "200",
+		"content", MediaType, "schema", "properties", "data")
+	assertJSON(t, related, `{"anyOf": [{"$ref": "#/components/schemas/posts"}, {"type": "null"}]}`)
+}
+
+func TestOpenAPI_components(t *testing.T) {
+	doc := openAPIDocument(t, new(Blog))
+
+	if got := objectKeys(t, jsonAt(t, doc, "components", "parameters")); !reflect.DeepEqual(got,
+		[]string{"fields", "filter", "id", "include", "page", "sort"}) {
+		t.Fatalf("Unexpected parameters %v", got)
+	}
+	assertJSON(t, jsonAt(t, doc, "components", "parameters", "fields", "style"), `"deepObject"`)
+
+	errs := jsonAt(t, doc, "components", "responses", "error", "content", MediaType, "schema")
+	assertJSON(t, jsonAt(t, errs, "required"), `["errors"]`)
+	if got := objectKeys(t, jsonAt(t, errs, "properties", "errors", "items", "properties")); !reflect.DeepEqual(got,
+		[]string{"code", "detail", "id", "meta", "source", "status", "title"}) {
+		t.Fatalf("Unexpected error object members %v", got)
+	}
+
+	// Every reference resolves within the document
+	var check func(v interface{})
+	check = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				if jsonAt(t, doc, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...) == nil {
+					t.Errorf("Unresolved reference %s", ref)
+				}
+			}
+			for _, e := range v {
+				check(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				check(e)
+			}
+		}
+	}
+	check(doc)
+}
+
+func TestOpenAPI_conflictingSchemas(t *testing.T) {
+	type otherBook struct {
+		ID    string `jsonapi:"primary,books"`
+		Title string `jsonapi:"attr,title"`
+	}
+	type library struct {
+		ID    string       `jsonapi:"primary,libraries"`
+		Books []*otherBook `jsonapi:"relation,books"`
+	}
+
+	_, err := newTestRegistry(t, new(Book), new(library)).OpenAPI("Libraries", "1.0.0")
+	if !errors.Is(err, ErrConflictingSchema) || !strings.Contains(err.Error(), `"books"`) {
+		t.Fatalf("Was expecting ErrConflictingSchema for books, got %v", err)
+	}
+}
+
+func TestOpenAPI_defaultRegistry(t *testing.T) {
+	Register(new(Person))
+
+	doc, err := OpenAPI("People", "2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok || paths["/people/{id}"] == nil {
+		t.Fatalf("Was expecting the paths of the registered people, got %v", doc["paths"])
+	}
+}
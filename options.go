@@ -0,0 +1,54 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+// Option configures optional behaviour of the marshal and unmarshal
+// functions. Options that do not apply to a given function are ignored.
+type Option func(*options)
+
+// options holds the configuration assembled from a list of Option values.
+type options struct {
+	collectErrors bool
+	naming        NamingStrategy
+	codec         Codec
+
+	strict            bool
+	disallowClientIDs bool
+
+	operation      operation
+	rejectReadOnly bool
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// CollectErrors makes the unmarshal functions continue through every
+// attribute and relationship of a resource instead of stopping at the first
+// failure. All failures are returned together as a MultiError whose entries
+// are *FieldError values pointing at the offending members. Without it, the
+// first failure is returned as is, e.g. ErrInvalidType.
+func CollectErrors() Option {
+	return func(o *options) {
+		o.collectErrors = true
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ErrUnregisteredType is returned by UnmarshalAny, wrapped in a *FieldError
+// pointing at the "type" member, for a resource object whose type has no
+// registered model.
+var ErrUnregisteredType = errors.New("resource type is not registered")
+
+// ResourceType describes a model registered in a Registry. It must not be
+// modified.
+type ResourceType struct {
+	// Name is the resource type given by the primary field.
+	Name string
+
+	// Type is the struct type of the model.
+	Type reflect.Type
+
+	// Attributes and Relationships list the members of the model in the
+	// order of their fields.
+	Attributes    []Member
+	Relationships []Member
+
+	// naming is the naming strategy the model was registered with.
+	naming NamingStrategy
+}
+
+// Member describes an attribute or relationship of a registered model.
+type Member struct {
+	// Name is the member name.
+	Name string
+
+	// Field is the name of the struct field holding the member.
+	Field string
+
+	// Type is the type of the struct field.
+	Type reflect.Type
+
+	// Options are the tag options following the member name, e.g.
+	// "omitempty".
+	Options []string
+
+	// RelatedType is the resource type of the related resources of a
+	// relationship.
+	RelatedType string
+
+	// ToMany reports whether a relationship holds a list of resources.
+	ToMany bool
+}
+
+// Registry records the models of resource types, so that their Go types can
+// be looked up by name and documents can be unmarshaled without knowing their
+// type in advance. The zero value is an empty registry ready to use, and a
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	byName map[string]*ResourceType
+	byType map[reflect.Type]*ResourceType
+}
+
+// DefaultRegistry is the registry used by Register and UnmarshalAny.
+var DefaultRegistry = new(Registry)
+
+// Register checks model, a struct or a struct pointer, like CheckModel and
+// adds it to the DefaultRegistry, so that a misconfigured model fails when the
+// service starts instead of on the first request that uses it. It panics on
+// the errors of Registry.Register.
+func Register(model interface{}, opts ...Option) {
+	if err := DefaultRegistry.Register(model, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// UnmarshalAny unmarshals a document whose primary data are resources of any
+// type registered in the DefaultRegistry. See Registry.UnmarshalAny.
+func UnmarshalAny(in io.Reader, opts ...Option) (interface{}, error) {
+	return DefaultRegistry.UnmarshalAny(in, opts...)
+}
+
+// OpenAPI generates an OpenAPI 3.1 document for the resource types registered
+// in the DefaultRegistry. See Registry.OpenAPI.
+func OpenAPI(title, version string) (map[string]interface{}, error) {
+	return DefaultRegistry.OpenAPI(title, version)
+}
+
+// Register checks model, a struct or a struct pointer, like CheckModel and
+// records it under its resource type. The options give the naming strategy
+// of its member names, which UnmarshalAny uses for the model. It returns an
+// error if the model is invalid, if another type was registered with the same
+// resource type, or if the type was registered with another naming strategy.
+// Registering a type again with the same naming strategy has no effect.
+func (r *Registry) Register(model interface{}, opts ...Option) error {
+	if err := CheckModel(model, opts...); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	rt := newResourceType(t, newOptions(opts).naming)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, ok := r.byName[rt.Name]; ok {
+		if other.Type != t {
+			return fmt.Errorf("jsonapi: resource type %q registered for both %v and %v", rt.Name, other.Type, t)
+		}
+		if other.naming != rt.naming {
+			return fmt.Errorf("jsonapi: %v registered with two naming strategies", t)
+		}
+		return nil
+	}
+
+	if r.byName == nil {
+		r.byName = make(map[string]*ResourceType)
+		r.byType = make(map[reflect.Type]*ResourceType)
+	}
+	r.byName[rt.Name] = rt
+	r.byType[t] = rt
+
+	return nil
+}
+
+// newResourceType describes the checked model type t.
+func newResourceType(t reflect.Type, naming NamingStrategy) *ResourceType {
+	info, _ := getModelInfo(t, naming)
+	rt := &ResourceType{Name: info.primaryName(), Type: t, naming: naming}
+
+	for _, field := range info.fields {
+		if field.annotation != annotationAttribute && field.annotation != annotationRelation {
+			continue
+		}
+
+		structField := t.Field(field.index)
+		member := Member{
+			Name:    field.name,
+			Field:   structField.Name,
+			Type:    structField.Type,
+			Options: field.args[2:],
+		}
+
+		if field.annotation == annotationAttribute {
+			rt.Attributes = append(rt.Attributes, member)
+		} else {
+			member.RelatedType = field.linkageType
+			if member.RelatedType == "" {
+				related, _ := getModelInfo(relatedType(structField.Type), naming)
+				member.RelatedType = related.primaryName()
+			}
+			member.ToMany = structField.Type.Kind() == reflect.Slice
+			rt.Relationships = append(rt.Relationships, member)
+		}
+	}
+
+	return rt
+}
+
+// Lookup returns the model registered with the resource type name.
+func (r *Registry) Lookup(name string) (*ResourceType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt, ok := r.byName[name]
+	return rt, ok
+}
+
+// LookupModel returns the registration of the type of model, a struct or a
+// struct pointer.
+func (r *Registry) LookupModel(model interface{}) (*ResourceType, bool) {
+	t := reflect.TypeOf(model)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt, ok := r.byType[t]
+	return rt, ok
+}
+
+// ResourceTypes returns the registered models sorted by resource type.
+func (r *Registry) ResourceTypes() []*ResourceType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]*ResourceType, 0, len(r.byName))
+	for _, rt := range r.byName {
+		types = append(types, rt)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+
+	return types
+}
+
+// anyPayload is a document whose primary data may be a resource object or an
+// array of them.
+type anyPayload struct {
+	Data     json.RawMessage `json:"data"`
+	Included []*Node         `json:"included,omitempty"`
+}
+
+// UnmarshalAny unmarshals a document into new models of the types registered
+// for its resource objects. It returns a struct pointer for a single resource
+// and an []interface{} of struct pointers for a collection, whose resources
+// may be of different types. A resource type without a registered model
+// returns ErrUnregisteredType.
+func (r *Registry) UnmarshalAny(in io.Reader, opts ...Option) (interface{}, error) {
+	payload := new(anyPayload)
+	o := newOptions(opts)
+
+	if err := o.decodeRequest(in, payload); err != nil {
+		return nil, err
+	}
+
+	ctx := newUnmarshalContext(payload.Included, o)
+
+	data := bytes.TrimSpace(payload.Data)
+	if len(data) == 0 || data[0] != '[' {
+		var node *Node
+		if len(data) > 0 {
+			if err := o.unmarshal(data, &node); err != nil {
+				return nil, err
+			}
+		}
+		return r.unmarshalResource(ctx, node, "/data")
+	}
+
+	var nodes []*Node
+	if err := o.unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+
+	models := []interface{}{}
+	var errs []error
+
+	for i, node := range nodes {
+		model, err := r.unmarshalResource(ctx, node, fmt.Sprintf("/data/%d", i))
+		if err != nil {
+			if !o.collectErrors {
+				return nil, err
+			}
+			errs = appendErrors(errs, err)
+		}
+		models = append(models, model)
+	}
+
+	if len(errs) > 0 {
+		return nil, MultiError(errs)
+	}
+
+	return models, nil
+}
+
+// unmarshalResource unmarshals data, the resource object at pointer, into a
+// new model of the type registered for it.
+func (r *Registry) unmarshalResource(ctx *unmarshalContext, data *Node, pointer string) (interface{}, error) {
+	if data == nil {
+		return nil, ErrNullData
+	}
+
+	rt, ok := r.Lookup(data.Type)
+	if !ok {
+		return nil, newFieldError(pointer+"/type", "", ErrUnregisteredType)
+	}
+
+	// The model is unmarshaled with the naming strategy it was registered
+	// with, whatever the options of the call
+	opts := *ctx.opts
+	opts.naming = rt.naming
+	rctx := &unmarshalContext{
+		opts:             &opts,
+		included:         ctx.included,
+		includedPointers: ctx.includedPointers,
+	}
+
+	model := reflect.New(rt.Type)
+	if err := rctx.unmarshalPrimary(data, model, pointer); err != nil {
+		return nil, err
+	}
+
+	return model.Interface(), nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T, models ...interface{}) *Registry {
+	t.Helper()
+
+	r := new(Registry)
+	for _, model := range models {
+		if err := r.Register(model); err != nil {
+			t.Fatalf("Unexpected error registering %T: %v", model, err)
+		}
+	}
+
+	return r
+}
+
+func TestRegistry_lookup(t *testing.T) {
+	r := newTestRegistry(t, new(Blog), Post{}, new(Note))
+
+	rt, ok := r.Lookup("blogs")
+	if !ok || rt.Type != reflect.TypeOf(Blog{}) {
+		t.Fatalf("Was expecting Blog for blogs, got %v", rt)
+	}
+	if _, ok := r.Lookup("comments"); ok {
+		t.Fatal("Was not expecting the unregistered comments")
+	}
+
+	rt, ok = r.LookupModel(&Post{})
+	if !ok || rt.Name != "posts" {
+		t.Fatalf("Was expecting posts for Post, got %v", rt)
+	}
+	if _, ok := r.LookupModel(Comment{}); ok {
+		t.Fatal("Was not expecting the unregistered Comment")
+	}
+
+	var names []string
+	for _, rt := range r.ResourceTypes() {
+		names = append(names, rt.Name)
+	}
+	if got := strings.Join(names, ","); got != "blogs,notes,posts" {
+		t.Fatalf("Was expecting the sorted resource types, got %s", got)
+	}
+}
+
+func TestRegistry_members(t *testing.T) {
+	r := newTestRegistry(t, new(Blog), new(Note))
+
+	rt, _ := r.Lookup("blogs")
+
+	var attrs []string
+	for _, m := range rt.Attributes {
+		attrs = append(attrs, m.Name)
+	}
+	if got := strings.Join(attrs, ","); got != "title,current_post_id,created_at,view_count" {
+		t.Fatalf("Unexpected attributes %s", got)
+	}
+	if m := rt.Attributes[2]; m.Field != "CreatedAt" || m.Type != timeType {
+		t.Fatalf("Unexpected created_at attribute %+v", m)
+	}
+
+	want := []Member{
+		{Name: "posts", Field: "Posts", Type: reflect.TypeOf([]*Post{}), Options: []string{}, RelatedType: "posts", ToMany: true},
+		{Name: "current_post", Field: "CurrentPost", Type: reflect.TypeOf(&Post{}), Options: []string{}, RelatedType: "posts"},
+	}
+	if !reflect.DeepEqual(rt.Relationships, want) {
+		t.Fatalf("Was expecting relationships %+v, got %+v", want, rt.Relationships)
+	}
+
+	// Identifier-only relations report the resource type of their tag
+	rt, _ = r.Lookup("notes")
+	for _, m := range rt.Relationships {
+		if m.RelatedType == "" {
+			t.Fatalf("Was expecting a related type for %s", m.Name)
+		}
+	}
+}
+
+func TestRegistry_register(t *testing.T) {
+	r := newTestRegistry(t, new(Blog))
+
+	if err := r.Register(Blog{}); err != nil {
+		t.Fatalf("Was expecting registering a type again to succeed, got %v", err)
+	}
+	if err := r.Register(Blog{}, WithNamingStrategy(KebabCase)); err == nil {
+		t.Fatal("Was expecting an error for another naming strategy")
+	}
+	if rt, _ := r.Lookup("blogs"); rt.naming != NoNamingStrategy {
+		t.Fatalf("Was expecting blogs to keep their naming strategy, got %v", rt.naming)
+	}
+
+	type Blogs struct {
+		ID string `jsonapi:"primary,blogs"`
+	}
+	if err := r.Register(new(Blogs)); err == nil {
+		t.Fatal("Was expecting an error for a second type of blogs")
+	}
+	if err := r.Register(new(BadModel)); !errors.Is(err, ErrBadJSONAPIStructTag) {
+		t.Fatalf("Was expecting the tag error of an invalid model, got %v", err)
+	}
+	if _, ok := r.Lookup("blogs"); !ok {
+		t.Fatal("Was expecting blogs to remain registered")
+	}
+}
+
+func TestRegistry_unmarshalAny(t *testing.T) {
+	r := newTestRegistry(t, new(Post), new(Comment))
+
+	doc := `{"data": {"type": "posts", "id": "1", "attributes": {"title": "Hello"},
+		"relationships": {"comments": {"data": [{"type": "comments", "id": "2"}]}}},
+		"included": [{"type": "comments", "id": "2", "attributes": {"body": "First"}}]}`
+
+	model, err := r.UnmarshalAny(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, ok := model.(*Post)
+	if !ok {
+		t.Fatalf("Was expecting a *Post, got %T", model)
+	}
+	if post.ID != 1 || post.Title != "Hello" || len(post.Comments) != 1 || post.Comments[0].Body != "First" {
+		t.Fatalf("Unexpected post %+v", post)
+	}
+}
+
+func TestRegistry_unmarshalAnyCollection(t *testing.T) {
+	r := newTestRegistry(t, new(Post), new(Comment))
+
+	doc := `{"data": [
+		{"type": "posts", "id": "1", "attributes": {"title": "Hello"}},
+		{"type": "comments", "id": "2", "attributes": {"body": "First"}}
+	]}`
+
+	model, err := r.UnmarshalAny(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, ok := model.([]interface{})
+	if !ok || len(models) != 2 {
+		t.Fatalf("Was expecting two models, got %#v", model)
+	}
+	if p, ok := models[0].(*Post); !ok || p.Title != "Hello" {
+		t.Fatalf("Was expecting the post first, got %#v", models[0])
+	}
+	if c, ok := models[1].(*Comment); !ok || c.Body != "First" {
+		t.Fatalf("Was expecting the comment second, got %#v", models[1])
+	}
+
+	model, err = r.UnmarshalAny(strings.NewReader(`{"data": []}`))
+	if err != nil || !reflect.DeepEqual(model, []interface{}{}) {
+		t.Fatalf("Was expecting an empty collection, got %#v, %v", model, err)
+	}
+}
+
+func TestRegistry_unmarshalAnyNamingStrategy(t *testing.T) {
+	r := new(Registry)
+	if err := r.Register(new(NamedArticle), WithNamingStrategy(KebabCase)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(new(Comment)); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := `{"data": [
+		{"type": "articles", "id": "1", "attributes": {"title": "Hello", "view-count": 3},
+			"relationships": {"lead-author": {"data": {"type": "comments", "id": "2"}}}},
+		{"type": "comments", "id": "3", "attributes": {"body": "First"}}
+	]}`
+
+	model, err := r.UnmarshalAny(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := model.([]interface{})
+	article, ok := models[0].(*NamedArticle)
+	if !ok || article.Title != "Hello" || article.ViewCount != 3 || article.LeadAuthor == nil || article.LeadAuthor.ID != 2 {
+		t.Fatalf("Unexpected article %#v", models[0])
+	}
+	if c, ok := models[1].(*Comment); !ok || c.Body != "First" {
+		t.Fatalf("Unexpected comment %#v", models[1])
+	}
+}
+
+func TestRegistry_unmarshalAnyErrors(t *testing.T) {
+	r := newTestRegistry(t, new(Post))
+
+	_, err := r.UnmarshalAny(strings.NewReader(`{"data": {"type": "blogs", "id": "1"}}`))
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Pointer != "/data/type" || !errors.Is(err, ErrUnregisteredType) {
+		t.Fatalf("Was expecting ErrUnregisteredType at /data/type, got %v", err)
+	}
+
+	if _, err := r.UnmarshalAny(strings.NewReader(`{"data": null}`)); err != ErrNullData {
+		t.Fatalf("Was expecting ErrNullData, got %v", err)
+	}
+
+	doc := `{"data": [{"type": "blogs", "id": "1"}, {"type": "posts", "id": "x"}]}`
+	_, err = r.UnmarshalAny(strings.NewReader(doc), CollectErrors())
+	var errs MultiError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Was expecting two errors, got %v", err)
+	}
+	if !errors.As(errs[0], &ferr) || ferr.Pointer != "/data/0/type" {
+		t.Fatalf("Was expecting the unregistered type first, got %v", errs[0])
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(new(Blog))
+	Register(Blog{})
+
+	type Blogs struct {
+		ID string `jsonapi:"primary,blogs"`
+	}
+	assertPanics(t, "another type with the same resource type", func() { Register(new(Blogs)) })
+	assertPanics(t, "an invalid model", func() { Register(new(BadModel)) })
+}
+
+func assertPanics(t *testing.T, desc string, fn func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Was expecting a panic for %s", desc)
+		}
+	}()
+	fn()
+}
@@ -15,8 +15,8 @@
 package jsonapi
 
 import (
-	"bytes"
-	"encoding/json"
+	"encoding"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -38,17 +38,78 @@ var (
 	// "iso8601" in the tag spec, but the JSON value was not an ISO8601 timestamp string.
 	ErrInvalidISO8601 = errors.New("Only strings can be parsed as dates, ISO8601 timestamps")
 	// ErrInvalidRFC3339 is returned when a struct has a time.Time type field and includes
-	// "rfc3339" in the tag spec, but the JSON value was not an RFC3339 timestamp string.
+	// "rfc3339" or "rfc3339nano" in the tag spec, but the JSON value was not an RFC3339
+	// timestamp string.
 	ErrInvalidRFC3339 = errors.New("Only strings can be parsed as dates, RFC3339 timestamps")
+	// ErrInvalidTimeFormat is returned when a struct has a time.Time type field and
+	// includes "date" or a name given to RegisterTimeFormat in the tag spec, but the
+	// JSON value was not a string in that layout.
+	ErrInvalidTimeFormat = errors.New("Only strings in the time format of the attribute can be parsed as dates")
+	// ErrInvalidDuration is returned when a struct has a time.Duration type field,
+	// but the JSON value was not a number, or an ISO8601 duration string when the tag
+	// spec includes "iso8601".
+	ErrInvalidDuration = errors.New("Only numbers or ISO8601 duration strings can be parsed as durations")
 	// ErrUnknownFieldNumberType is returned when the JSON value was a float
 	// (numeric) but the Struct field was a non numeric type (i.e. not int, uint,
 	// float, etc)
 	ErrUnknownFieldNumberType = errors.New("The struct field was not of a known number type")
 	// ErrInvalidType is returned when the given type is incompatible with the expected type.
 	ErrInvalidType = errors.New("Invalid type provided") // I wish we used punctuation.
-
+	// ErrMalformedRelationship is returned when a relationship object, or the
+	// resource linkage it holds, does not have the structure required by the
+	// JSON API specification.
+	ErrMalformedRelationship = errors.New("jsonapi: malformed relationship")
+	// ErrNullData is returned when a resource object to unmarshal into a model,
+	// such as the primary data of the document, is null.
+	ErrNullData = errors.New("jsonapi: resource object is null")
 )
 
+// InvalidUnmarshalError is returned when the model given to an unmarshal
+// function is not a non-nil pointer to a struct, or the type given to
+// UnmarshalManyPayload is not a struct pointer type.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	switch {
+	case e.Type == nil:
+		return "jsonapi: Unmarshal(nil)"
+	case e.Type.Kind() != reflect.Ptr:
+		return "jsonapi: Unmarshal(non-pointer " + e.Type.String() + ")"
+	case e.Type.Elem().Kind() != reflect.Struct:
+		return "jsonapi: Unmarshal(pointer to non-struct " + e.Type.String() + ")"
+	}
+
+	return "jsonapi: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// checkModelType returns an *InvalidUnmarshalError unless t is a struct
+// pointer type.
+func checkModelType(t reflect.Type) error {
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return &InvalidUnmarshalError{t}
+	}
+
+	return nil
+}
+
+// checkModel returns an *InvalidUnmarshalError unless model is a non-nil
+// struct pointer.
+func checkModel(model reflect.Value) error {
+	if !model.IsValid() {
+		return &InvalidUnmarshalError{}
+	}
+	if err := checkModelType(model.Type()); err != nil {
+		return err
+	}
+	if model.IsNil() {
+		return &InvalidUnmarshalError{model.Type()}
+	}
+
+	return nil
+}
+
 // ErrUnsupportedPtrType is returned when the Struct field was a pointer but
 // the JSON value was of a different type
 type ErrUnsupportedPtrType struct {
@@ -105,216 +166,334 @@ func newErrUnsupportedPtrType(rf reflect.Value, t reflect.Type, structField refl
 // Visit https://github.com/companyinfo/jsonapi#create for more info.
 //
 // model interface{} should be a pointer to a struct.
-func UnmarshalPayload(in io.Reader, model interface{}) error {
+//
+// By default unmarshaling stops at the first invalid member; pass the
+// CollectErrors option to receive every failure at once.
+func UnmarshalPayload(in io.Reader, model interface{}, opts ...Option) error {
 	payload := new(OnePayload)
+	o := newOptions(opts)
 
-	if err := json.NewDecoder(in).Decode(payload); err != nil {
+	if err := o.decodeRequest(in, payload); err != nil {
 		return err
 	}
 
-	if payload.Included != nil {
-		includedMap := make(map[string]*Node)
-		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
-		}
+	ctx := newUnmarshalContext(payload.Included, o)
 
-		return unmarshalNode(payload.Data, reflect.ValueOf(model), &includedMap)
-	}
-	return unmarshalNode(payload.Data, reflect.ValueOf(model), nil)
+	return ctx.unmarshalPrimary(payload.Data, reflect.ValueOf(model), "/data")
 }
 
 // UnmarshalManyPayload converts an io into a set of struct instances using
 // jsonapi tags on the type's struct fields.
-func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
+func UnmarshalManyPayload(in io.Reader, t reflect.Type, opts ...Option) ([]interface{}, error) {
+	if err := checkModelType(t); err != nil {
+		return nil, err
+	}
+
 	payload := new(ManyPayload)
+	o := newOptions(opts)
 
-	if err := json.NewDecoder(in).Decode(payload); err != nil {
+	if err := o.decodeRequest(in, payload); err != nil {
 		return nil, err
 	}
 
-	models := []interface{}{}         // will be populated from the "data"
-	includedMap := map[string]*Node{} // will be populate from the "included"
+	ctx := newUnmarshalContext(payload.Included, o)
 
-	if payload.Included != nil {
-		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
-		}
-	}
+	models := []interface{}{} // will be populated from the "data"
+	var errs []error
 
-	for _, data := range payload.Data {
+	for i, data := range payload.Data {
 		model := reflect.New(t.Elem())
-		err := unmarshalNode(data, model, &includedMap)
+		err := ctx.unmarshalPrimary(data, model, fmt.Sprintf("/data/%d", i))
 		if err != nil {
-			return nil, err
+			if !o.collectErrors {
+				return nil, err
+			}
+			errs = appendErrors(errs, err)
 		}
 		models = append(models, model.Interface())
 	}
 
+	if len(errs) > 0 {
+		return nil, MultiError(errs)
+	}
+
 	return models, nil
 }
 
-func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("data is not a jsonapi representation of '%v'", model.Type())
+// unmarshalContext holds the state shared while unmarshaling the resource
+// objects of a single document.
+type unmarshalContext struct {
+	opts *options
+
+	// included maps "type,id" keys to the resource objects of the "included"
+	// member, and includedPointers maps the same keys to their JSON Pointer.
+	included         map[string]*Node
+	includedPointers map[string]string
+}
+
+func newUnmarshalContext(included []*Node, opts *options) *unmarshalContext {
+	ctx := &unmarshalContext{
+		opts:             opts,
+		included:         make(map[string]*Node, len(included)),
+		includedPointers: make(map[string]string, len(included)),
+	}
+	ctx.addIncluded(included)
+
+	return ctx
+}
+
+// addIncluded makes the resource objects of the "included" member available
+// to resolve relationships.
+func (ctx *unmarshalContext) addIncluded(included []*Node) {
+	for i, n := range included {
+		if n == nil {
+			continue
 		}
-	}()
+		key := fmt.Sprintf("%s,%s", n.Type, n.ID)
+		ctx.included[key] = n
+		ctx.includedPointers[key] = fmt.Sprintf("/included/%d", i)
+	}
+}
+
+// fullNode returns the included resource object matching the identifier n,
+// or n itself when there is none. The returned pointer locates the node in
+// the document; pointer is used when n is not included.
+func (ctx *unmarshalContext) fullNode(n *Node, pointer string) (*Node, string) {
+	includedKey := fmt.Sprintf("%s,%s", n.Type, n.ID)
+
+	if node, ok := ctx.included[includedKey]; ok {
+		return node, ctx.includedPointers[includedKey]
+	}
+
+	return n, pointer
+}
+
+// validateAbsent checks the constraints of a member without a value in the
+// document; present reports whether it was given as null. Update requests
+// only carry the members that change, so a member they leave out is never
+// required.
+func (ctx *unmarshalContext) validateAbsent(structField reflect.StructField, name string, value reflect.Value, present bool) error {
+	if !present && ctx.opts.operation == operationUpdate {
+		return nil
+	}
+
+	return validateMember(structField, name, value, false)
+}
+
+// unmarshalNode populates model from data. pointer is the JSON Pointer of
+// data within the document and is used to locate the members that failed.
+func (ctx *unmarshalContext) unmarshalNode(data *Node, model reflect.Value, pointer string) error {
+	if data == nil {
+		return ErrNullData
+	}
 
 	modelValue := model.Elem()
 	modelType := modelValue.Type()
 
-	var er error
+	var errs []error
 
-	for i := 0; i < modelValue.NumField(); i++ {
-		fieldType := modelType.Field(i)
-		tag := fieldType.Tag.Get("jsonapi")
-		if tag == "" {
-			continue
+	// fail records an error for the member at the JSON Pointer at and reports
+	// whether the remaining fields should be skipped. Without CollectErrors
+	// the first error is kept as is, so that callers comparing it with the
+	// sentinel errors keep working.
+	fail := func(at, field string, err error) bool {
+		if !ctx.opts.collectErrors {
+			errs = append(errs, err)
+			return true
 		}
 
-		fieldValue := modelValue.Field(i)
-
-		args := strings.Split(tag, ",")
-		if len(args) < 1 {
-			er = ErrBadJSONAPIStructTag
-			break
+		switch err.(type) {
+		case MultiError:
+			errs = appendErrors(errs, err)
+		case *FieldError:
+			errs = append(errs, err)
+		default:
+			errs = append(errs, newFieldError(at, field, err))
 		}
+		return false
+	}
+
+	info, err := getModelInfo(modelType, ctx.opts.naming)
+	if err != nil {
+		return err
+	}
 
-		annotation := args[0]
+	if ctx.opts.strict && checkMembers(data, info, pointer, fail) {
+		return errs[0]
+	}
 
-		if (annotation == annotationClientID && len(args) != 1) ||
-			(annotation != annotationClientID && len(args) < 2) {
-			er = ErrBadJSONAPIStructTag
-			break
-		}
+fields:
+	for _, field := range info.fields {
+		fieldType := modelType.Field(field.index)
+		fieldValue := modelValue.Field(field.index)
+
+		args := field.args
+		annotation := field.annotation
 
 		if annotation == annotationPrimary {
 			// Check the JSON API Type
 			if data.Type != args[1] {
-				er = fmt.Errorf(
+				err := fmt.Errorf(
 					"Trying to Unmarshal an object of type %#v, but %#v does not match",
 					data.Type,
 					args[1],
 				)
-				break
-			}
-
-			if data.ID == "" {
+				if fail(pointer+"/type", fieldType.Name, err) {
+					break
+				}
 				continue
 			}
 
-			// ID will have to be transmitted as astring per the JSON API spec
-			v := reflect.ValueOf(data.ID)
-
-			// Deal with PTRS
-			var kind reflect.Kind
-			if fieldValue.Kind() == reflect.Ptr {
-				kind = fieldType.Type.Elem().Kind()
-			} else {
-				kind = fieldType.Type.Kind()
-			}
-
-			// Handle String case
-			if kind == reflect.String {
-				assign(fieldValue, v)
+			if data.ID == "" {
 				continue
 			}
 
-			// Value was not a string... only other supported type was a numeric,
-			// which would have been sent as a float value.
-			floatValue, err := strconv.ParseFloat(data.ID, 64)
-			if err != nil {
-				// Could not convert the value in the "id" attr to a float
-				er = ErrBadJSONAPIID
-				break
-			}
-
-			// Convert the numeric float to one of the supported ID numeric types
-			// (int[8,16,32,64] or uint[8,16,32,64])
-			idValue, err := handleNumeric(floatValue, fieldType.Type, fieldValue)
-			if err != nil {
-				// We had a JSON float (numeric), but our field was not one of the
-				// allowed numeric types
-				er = ErrBadJSONAPIID
-				break
+			if err := setID(fieldValue, data.ID); err != nil {
+				if fail(pointer+"/id", fieldType.Name, err) {
+					break
+				}
 			}
-
-			assign(fieldValue, idValue)
 		} else if annotation == annotationClientID {
 			if data.ClientID == "" {
 				continue
 			}
 
-			fieldValue.Set(reflect.ValueOf(data.ClientID))
+			fieldValue.SetString(data.ClientID)
 		} else if annotation == annotationAttribute {
-			attributes := data.Attributes
+			attrPointer := memberPointer(pointer, "attributes", args[1])
+			attribute, present := data.Attributes[args[1]]
 
-			if attributes == nil || len(data.Attributes) == 0 {
+			if ctx.opts.readOnly(field) {
+				if present && ctx.opts.rejectReadOnly {
+					if fail(attrPointer, fieldType.Name, newFieldError(attrPointer, fieldType.Name, ErrReadOnly)) {
+						break
+					}
+				}
 				continue
 			}
 
-			attribute := attributes[args[1]]
-
 			// continue if the attribute was not included in the request
 			if attribute == nil {
+				if err := ctx.validateAbsent(fieldType, args[1], fieldValue, present); err != nil {
+					if fail(attrPointer, fieldType.Name, newFieldError(attrPointer, fieldType.Name, err)) {
+						break
+					}
+				}
 				continue
 			}
 
 			structField := fieldType
-			value, err := unmarshalAttribute(attribute, args, structField, fieldValue)
+			value, err := ctx.unmarshalAttribute(attribute, args, structField, fieldValue)
+			switch err.(type) {
+			case *FieldError, MultiError:
+				// Errors within nested structs, maps and slices are located
+				// relative to the attribute.
+				err = locateError(attrPointer, fieldType.Name, err)
+			}
 			if err != nil {
-				er = err
-				break
+				if fail(attrPointer, fieldType.Name, err) {
+					break
+				}
+				continue
 			}
 
 			assign(fieldValue, value)
+
+			if err := validateMember(fieldType, args[1], fieldValue, true); err != nil {
+				if fail(attrPointer, fieldType.Name, newFieldError(attrPointer, fieldType.Name, err)) {
+					break
+				}
+			}
 		} else if annotation == annotationRelation {
 			isSlice := fieldValue.Type().Kind() == reflect.Slice
+			relPointer := memberPointer(pointer, "relationships", args[1])
 
-			if data.Relationships == nil || data.Relationships[args[1]] == nil {
+			if ctx.opts.readOnly(field) {
+				if _, present := data.Relationships[args[1]]; present && ctx.opts.rejectReadOnly {
+					if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, ErrReadOnly)) {
+						break
+					}
+				}
 				continue
 			}
 
-			if isSlice {
-				// to-many relationship
-				relationship := new(RelationshipManyNode)
+			linkage, present, err := relationshipData(data.Relationships[args[1]])
+			if err != nil {
+				if fail(relPointer, fieldType.Name, err) {
+					break
+				}
+				continue
+			}
 
-				buf := bytes.NewBuffer(nil)
+			if !present {
+				if err := ctx.validateAbsent(fieldType, args[1], fieldValue, false); err != nil {
+					if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, err)) {
+						break
+					}
+				}
+				continue
+			}
 
-				json.NewEncoder(buf).Encode(data.Relationships[args[1]])
-				json.NewDecoder(buf).Decode(relationship)
+			if field.linkageType != "" {
+				// identifier-only relationship
+				if unmarshalIdentifiers(linkage, field.linkageType, fieldValue, relPointer, fieldType.Name, fail) {
+					break
+				}
+
+				if err := validateMember(fieldType, args[1], fieldValue, linkage != nil); err != nil {
+					if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, err)) {
+						break
+					}
+				}
+				continue
+			}
+
+			if isSlice {
+				// to-many relationship
+				data, err := toManyLinkage(linkage)
+				if err != nil {
+					if fail(relPointer, fieldType.Name, err) {
+						break
+					}
+					continue
+				}
 
-				data := relationship.Data
 				models := reflect.New(fieldValue.Type()).Elem()
+				isPtr := fieldValue.Type().Elem().Kind() == reflect.Ptr
 
-				for _, n := range data {
-					m := reflect.New(fieldValue.Type().Elem().Elem())
+				for j, n := range data {
+					m := reflect.New(relatedType(fieldValue.Type()))
 
-					if err := unmarshalNode(
-						fullNode(n, included),
-						m,
-						included,
-					); err != nil {
-						er = err
-						break
+					node, nodePointer := ctx.fullNode(n, fmt.Sprintf("%s/data/%d", relPointer, j))
+					if err := ctx.unmarshalNode(node, m, nodePointer); err != nil {
+						if fail(nodePointer, fieldType.Name, err) {
+							break fields
+						}
+						continue
 					}
 
+					if !isPtr {
+						m = m.Elem()
+					}
 					models = reflect.Append(models, m)
 				}
 
 				fieldValue.Set(models)
+
+				if err := validateMember(fieldType, args[1], fieldValue, true); err != nil {
+					if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, err)) {
+						break
+					}
+				}
 			} else {
 				// to-one relationships
-				relationship := new(RelationshipOneNode)
-
-				buf := bytes.NewBuffer(nil)
-
-				json.NewEncoder(buf).Encode(
-					data.Relationships[args[1]],
-				)
-				json.NewDecoder(buf).Decode(relationship)
+				relData, err := toOneLinkage(linkage)
+				if err != nil {
+					if fail(relPointer, fieldType.Name, err) {
+						break
+					}
+					continue
+				}
 
 				/*
 					http://jsonapi.org/format/#document-resource-object-relationships
@@ -322,40 +501,240 @@ func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node)
 					relationship can have a data node set to null (e.g. to disassociate the relationship)
 					so unmarshal and set fieldValue only if data obj is not null
 				*/
-				if relationship.Data == nil {
+				if relData == nil {
+					if err := validateMember(fieldType, args[1], fieldValue, false); err != nil {
+						if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, err)) {
+							break
+						}
+					}
 					continue
 				}
 
-				m := reflect.New(fieldValue.Type().Elem())
-				if err := unmarshalNode(
-					fullNode(relationship.Data, included),
-					m,
-					included,
-				); err != nil {
-					er = err
-					break
+				m := reflect.New(relatedType(fieldValue.Type()))
+				node, nodePointer := ctx.fullNode(relData, relPointer+"/data")
+				if err := ctx.unmarshalNode(node, m, nodePointer); err != nil {
+					if fail(nodePointer, fieldType.Name, err) {
+						break
+					}
+					continue
 				}
 
-				fieldValue.Set(m)
+				if fieldValue.Kind() == reflect.Ptr {
+					fieldValue.Set(m)
+				} else {
+					fieldValue.Set(m.Elem())
+				}
 
+				if err := validateMember(fieldType, args[1], fieldValue, true); err != nil {
+					if fail(relPointer, fieldType.Name, newFieldError(relPointer, fieldType.Name, err)) {
+						break
+					}
+				}
 			}
 
-		} else {
-			er = fmt.Errorf(unsupportedStructTagMsg, annotation)
 		}
 	}
 
-	return er
+	if len(errs) == 0 {
+		return nil
+	}
+	if !ctx.opts.collectErrors {
+		return errs[0]
+	}
+
+	return MultiError(errs)
 }
 
-func fullNode(n *Node, included *map[string]*Node) *Node {
-	includedKey := fmt.Sprintf("%s,%s", n.Type, n.ID)
+// unmarshalIdentifiers sets fieldValue, an identifier-only relation of
+// resources of type typ, to the ids of the resource linkage of the
+// relationship at pointer. Like fail, it reports whether unmarshaling stops.
+func unmarshalIdentifiers(linkage interface{}, typ string, fieldValue reflect.Value,
+	pointer, name string, fail func(at, field string, err error) bool) bool {
+	setIdentifier := func(v reflect.Value, n *Node, at string) bool {
+		if n.Type != typ {
+			err := fmt.Errorf(
+				"Trying to Unmarshal an object of type %#v, but %#v does not match",
+				n.Type,
+				typ,
+			)
+			return fail(at+"/type", name, err)
+		}
+		if err := setID(v, n.ID); err != nil {
+			return fail(at+"/id", name, err)
+		}
+		return false
+	}
+
+	if fieldValue.Kind() != reflect.Slice {
+		n, err := toOneLinkage(linkage)
+		if err != nil {
+			return fail(pointer, name, err)
+		}
+		if n == nil {
+			return false
+		}
+
+		id := reflect.New(fieldValue.Type()).Elem()
+		if stop := setIdentifier(id, n, pointer+"/data"); stop || id.IsZero() {
+			return stop
+		}
+		fieldValue.Set(id)
+
+		return false
+	}
+
+	nodes, err := toManyLinkage(linkage)
+	if err != nil {
+		return fail(pointer, name, err)
+	}
+
+	ids := reflect.MakeSlice(fieldValue.Type(), len(nodes), len(nodes))
+	for i, n := range nodes {
+		if setIdentifier(ids.Index(i), n, fmt.Sprintf("%s/data/%d", pointer, i)) {
+			return true
+		}
+	}
+	fieldValue.Set(ids)
+
+	return false
+}
+
+// memberPointer returns the JSON Pointer of the member name within the given
+// object (e.g. "attributes") of the resource object at pointer.
+func memberPointer(pointer, object, name string) string {
+	return pointerTo(pointer+"/"+object, name)
+}
+
+// relationshipData returns the "data" member of the relationship object rel
+// as decoded by encoding/json, and whether rel holds resource linkage at all.
+// A relationship with only "links" or "meta" is treated as absent.
+func relationshipData(rel interface{}) (interface{}, bool, error) {
+	if rel == nil {
+		return nil, false, nil
+	}
+
+	obj, ok := rel.(map[string]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("%w: relationship must be an object", ErrMalformedRelationship)
+	}
+
+	data, ok := obj["data"]
+	return data, ok, nil
+}
+
+// toOneLinkage converts the resource linkage of a to-one relationship, null
+// or a single resource object, into a Node.
+func toOneLinkage(data interface{}) (*Node, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if _, ok := data.([]interface{}); ok {
+		return nil, fmt.Errorf("%w: to-one relationship data must be an object or null", ErrMalformedRelationship)
+	}
+
+	return linkageNode(data)
+}
 
-	if included != nil && (*included)[includedKey] != nil {
-		return (*included)[includedKey]
+// toManyLinkage converts the resource linkage of a to-many relationship, an
+// array of resource objects, into Nodes.
+func toManyLinkage(data interface{}) ([]*Node, error) {
+	items, ok := data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: to-many relationship data must be an array", ErrMalformedRelationship)
 	}
 
-	return n
+	nodes := make([]*Node, 0, len(items))
+	for _, item := range items {
+		node, err := linkageNode(item)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// linkageNode converts a resource identifier object, or an embedded resource
+// object, into a Node.
+func linkageNode(v interface{}) (*Node, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: resource linkage must hold objects", ErrMalformedRelationship)
+	}
+
+	typ, ok := obj["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: resource identifier must have a string \"type\"", ErrMalformedRelationship)
+	}
+
+	node := &Node{Type: typ}
+	var err error
+
+	if node.ID, err = linkageString(obj, "id"); err != nil {
+		return nil, err
+	}
+	if node.ClientID, err = linkageString(obj, "client-id"); err != nil {
+		return nil, err
+	}
+	if node.Attributes, err = linkageObject(obj, "attributes"); err != nil {
+		return nil, err
+	}
+	if node.Relationships, err = linkageObject(obj, "relationships"); err != nil {
+		return nil, err
+	}
+
+	links, err := linkageObject(obj, "links")
+	if err != nil {
+		return nil, err
+	}
+	if links != nil {
+		l := Links(links)
+		node.Links = &l
+	}
+
+	meta, err := linkageObject(obj, "meta")
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil {
+		m := Meta(meta)
+		node.Meta = &m
+	}
+
+	return node, nil
+}
+
+// linkageString returns the string member name of obj, or "" when it is
+// absent or null.
+func linkageString(obj map[string]interface{}, name string) (string, error) {
+	value := obj[name]
+	if value == nil {
+		return "", nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %q must be a string", ErrMalformedRelationship, name)
+	}
+
+	return s, nil
+}
+
+// linkageObject returns the object member name of obj, or nil when it is
+// absent or null.
+func linkageObject(obj map[string]interface{}, name string) (map[string]interface{}, error) {
+	value := obj[name]
+	if value == nil {
+		return nil, nil
+	}
+
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %q must be an object", ErrMalformedRelationship, name)
+	}
+
+	return m, nil
 }
 
 // assign will take the value specified and assign it to the field; if
@@ -395,7 +774,7 @@ func assignValue(field, value reflect.Value) {
 	}
 }
 
-func unmarshalAttribute(
+func (ctx *unmarshalContext) unmarshalAttribute(
 	attribute interface{},
 	args []string,
 	structField reflect.StructField,
@@ -403,29 +782,38 @@ func unmarshalAttribute(
 	value = reflect.ValueOf(attribute)
 	fieldType := structField.Type
 
-	// Handle field of type []string
-	if fieldValue.Type() == reflect.TypeOf([]string{}) {
-		value, err = handleStringSlice(attribute)
-		return
+	// Handle field of type time.Time, time.Duration or a nullable
+	// database/sql type, or a pointer to one
+	t := fieldValue.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-
-	// Handle field of type time.Time
-	if fieldValue.Type() == reflect.TypeOf(time.Time{}) ||
-		fieldValue.Type() == reflect.TypeOf(new(time.Time)) {
+	if isTimeType(t) {
 		value, err = handleTime(attribute, args, fieldValue)
 		return
 	}
+	if t == durationType {
+		value, err = handleDuration(attribute, args, fieldValue)
+		return
+	}
+	if _, ok := nullValueIndex(t); ok {
+		value, err = ctx.handleNull(attribute, args, fieldValue.Type(), structField.Name)
+		return
+	}
 
 	// Handle field of type struct
 	if fieldValue.Type().Kind() == reflect.Struct {
-		value, err = handleStruct(attribute, fieldValue)
+		value, err = ctx.handleStruct(attribute, fieldValue)
 		return
 	}
 
-	// Handle field containing slice of structs
-	if fieldValue.Type().Kind() == reflect.Slice &&
-		reflect.TypeOf(fieldValue.Interface()).Elem().Kind() == reflect.Struct {
-		value, err = handleStructSlice(attribute, fieldValue)
+	// Handle field of type map, slice or array, or a pointer to one
+	switch t.Kind() {
+	case reflect.Map:
+		value, err = ctx.handleMap(attribute, args, t, structField.Name)
+		return
+	case reflect.Slice, reflect.Array:
+		value, err = ctx.handleSlice(attribute, args, t, structField.Name)
 		return
 	}
 
@@ -437,7 +825,7 @@ func unmarshalAttribute(
 
 	// Field was a Pointer type
 	if fieldValue.Kind() == reflect.Ptr {
-		value, err = handlePointer(attribute, args, fieldType, fieldValue, structField)
+		value, err = ctx.handlePointer(attribute, args, fieldType, fieldValue, structField)
 		return
 	}
 
@@ -450,77 +838,25 @@ func unmarshalAttribute(
 	return
 }
 
-func handleStringSlice(attribute interface{}) (reflect.Value, error) {
-	v := reflect.ValueOf(attribute)
-	values := make([]string, v.Len())
-	for i := 0; i < v.Len(); i++ {
-		values[i] = v.Index(i).Interface().(string)
-	}
-
-	return reflect.ValueOf(values), nil
-}
-
 func handleTime(attribute interface{}, args []string, fieldValue reflect.Value) (reflect.Value, error) {
-	var isISO8601, isRFC3339 bool
-	v := reflect.ValueOf(attribute)
-
-	if len(args) > 2 {
-		for _, arg := range args[2:] {
-			if arg == annotationISO8601 {
-				isISO8601 = true
-			} else if arg == annotationRFC3339 {
-				isRFC3339 = true
-			}
-		}
-	}
-
-	if isISO8601 {
-		if v.Kind() != reflect.String {
-			return reflect.ValueOf(time.Now()), ErrInvalidISO8601
-		}
-
-		t, err := time.Parse(iso8601TimeFormat, v.Interface().(string))
-		if err != nil {
-			return reflect.ValueOf(time.Now()), ErrInvalidISO8601
-		}
-
-		if fieldValue.Kind() == reflect.Ptr {
-			return reflect.ValueOf(&t), nil
-		}
-
-		return reflect.ValueOf(t), nil
+	t, err := parseTimeFormat(args).parse(reflect.ValueOf(attribute))
+	if err != nil {
+		return reflect.ValueOf(time.Now()), err
 	}
 
-	if isRFC3339 {
-		if v.Kind() != reflect.String {
-			return reflect.ValueOf(time.Now()), ErrInvalidRFC3339
-		}
-
-		t, err := time.Parse(time.RFC3339, v.Interface().(string))
-		if err != nil {
-			return reflect.ValueOf(time.Now()), ErrInvalidRFC3339
-		}
-
-		if fieldValue.Kind() == reflect.Ptr {
-			return reflect.ValueOf(&t), nil
-		}
-
-		return reflect.ValueOf(t), nil
+	// Named time types are converted from time.Time.
+	fieldType := fieldValue.Type()
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
 	}
+	value := reflect.New(fieldType)
+	value.Elem().Set(reflect.ValueOf(t).Convert(fieldType))
 
-	var at int64
-
-	if v.Kind() == reflect.Float64 {
-		at = int64(v.Interface().(float64))
-	} else if v.Kind() == reflect.Int {
-		at = v.Int()
-	} else {
-		return reflect.ValueOf(time.Now()), ErrInvalidTime
+	if fieldValue.Kind() == reflect.Ptr {
+		return value, nil
 	}
 
-	t := time.Unix(at, 0)
-
-	return reflect.ValueOf(t), nil
+	return value.Elem(), nil
 }
 
 func handleNumeric(
@@ -583,7 +919,7 @@ func handleNumeric(
 	return numericValue, nil
 }
 
-func handlePointer(
+func (ctx *unmarshalContext) handlePointer(
 	attribute interface{},
 	args []string,
 	fieldType reflect.Type,
@@ -600,13 +936,21 @@ func handlePointer(
 	case complex64, complex128, uintptr:
 		concreteVal = reflect.ValueOf(&cVal)
 	case map[string]interface{}:
+		if t.Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, newErrUnsupportedPtrType(
+				reflect.ValueOf(attribute), fieldType, structField)
+		}
 		var err error
-		concreteVal, err = handleStruct(attribute, fieldValue)
-		if err != nil {
+		concreteVal, err = ctx.handleStruct(attribute, fieldValue)
+		switch err.(type) {
+		case nil:
+			return concreteVal, nil
+		case *FieldError, MultiError:
+			return reflect.Value{}, err
+		default:
 			return reflect.Value{}, newErrUnsupportedPtrType(
 				reflect.ValueOf(attribute), fieldType, structField)
 		}
-		return concreteVal, err
 	default:
 		return reflect.Value{}, newErrUnsupportedPtrType(
 			reflect.ValueOf(attribute), fieldType, structField)
@@ -620,19 +964,15 @@ func handlePointer(
 	return concreteVal, nil
 }
 
-func handleStruct(
+func (ctx *unmarshalContext) handleStruct(
 	attribute interface{},
 	fieldValue reflect.Value) (reflect.Value, error) {
 
-	data, err := json.Marshal(attribute)
-	if err != nil {
-		return reflect.Value{}, err
-	}
-
-	node := new(Node)
-	if err := json.Unmarshal(data, &node.Attributes); err != nil {
-		return reflect.Value{}, err
+	attributes, ok := attribute.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, ErrInvalidType
 	}
+	node := &Node{Attributes: attributes}
 
 	var model reflect.Value
 	if fieldValue.Kind() == reflect.Ptr {
@@ -641,29 +981,208 @@ func handleStruct(
 		model = reflect.New(fieldValue.Type())
 	}
 
-	if err := unmarshalNode(node, model, nil); err != nil {
-		return reflect.Value{}, err
+	t := model.Type().Elem()
+	if !hasJSONAPITags(t) || model.Type().Implements(jsonUnmarshalerType) {
+		// A plain JSON value object.
+		b, err := ctx.opts.marshal(attributes)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := ctx.opts.unmarshal(b, model.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return model, nil
+	}
+
+	// Errors are collected to locate them within the nested struct; the
+	// caller reports them relative to the attribute.
+	opts := *ctx.opts
+	opts.collectErrors = true
+
+	nested := newUnmarshalContext(nil, &opts)
+	if err := nested.unmarshalNode(node, model, ""); err != nil {
+		merr, ok := err.(MultiError)
+		if !ok {
+			return reflect.Value{}, err
+		}
+		for i, e := range merr {
+			if ferr, ok := e.(*FieldError); ok {
+				merr[i] = newFieldError(strings.TrimPrefix(ferr.Pointer, "/attributes"), ferr.Field, ferr.Err)
+			}
+		}
+		if !ctx.opts.collectErrors {
+			return reflect.Value{}, merr[0]
+		}
+		return reflect.Value{}, merr
 	}
 
 	return model, nil
 }
 
-func handleStructSlice(
+// handleMap returns the map of type t held by the JSON object attribute of
+// the struct field name. Its values are unmarshaled like attributes of the
+// element type of t.
+func (ctx *unmarshalContext) handleMap(
 	attribute interface{},
-	fieldValue reflect.Value) (reflect.Value, error) {
-	models := reflect.New(fieldValue.Type()).Elem()
-	dataMap := reflect.ValueOf(attribute).Interface().([]interface{})
-	for _, data := range dataMap {
-		model := reflect.New(fieldValue.Type().Elem()).Elem()
+	args []string,
+	t reflect.Type,
+	name string) (reflect.Value, error) {
+	obj, ok := attribute.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, ErrInvalidType
+	}
 
-		value, err := handleStruct(data, model)
+	m := reflect.MakeMapWithSize(t, len(obj))
+	for _, member := range sortedKeys(obj) {
+		key, err := parseMapKey(member, t.Key())
+		if err != nil {
+			return reflect.Value{}, elementError(member, err)
+		}
 
+		elem, err := ctx.unmarshalElement(obj[member], args, t.Elem(), name)
 		if err != nil {
-			continue
+			return reflect.Value{}, elementError(member, err)
 		}
 
-		models = reflect.Append(models, reflect.Indirect(value))
+		m.SetMapIndex(key, elem)
 	}
 
-	return models, nil
+	return m, nil
+}
+
+// handleSlice returns the slice or array of type t held by the JSON array
+// attribute of the struct field name. Its elements are unmarshaled like
+// attributes of the element type of t. As with encoding/json, a byte slice
+// is decoded from a base64 string, and the elements of a JSON array longer
+// than an array type are discarded.
+func (ctx *unmarshalContext) handleSlice(
+	attribute interface{},
+	args []string,
+	t reflect.Type,
+	name string) (reflect.Value, error) {
+	if s, ok := attribute.(string); ok && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return reflect.Value{}, ErrInvalidType
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	}
+
+	items, ok := attribute.([]interface{})
+	if !ok {
+		return reflect.Value{}, ErrInvalidType
+	}
+
+	var s reflect.Value
+	if t.Kind() == reflect.Array {
+		s = reflect.New(t).Elem()
+	} else {
+		s = reflect.MakeSlice(t, len(items), len(items))
+	}
+
+	for i, item := range items {
+		if i == s.Len() {
+			break
+		}
+
+		elem, err := ctx.unmarshalElement(item, args, t.Elem(), name)
+		if err != nil {
+			return reflect.Value{}, elementError(strconv.Itoa(i), err)
+		}
+		s.Index(i).Set(elem)
+	}
+
+	return s, nil
+}
+
+// unmarshalElement returns the value of type t held by v, an element of a
+// map or slice attribute of the struct field name. null leaves the zero
+// value, as encoding/json does.
+func (ctx *unmarshalContext) unmarshalElement(
+	v interface{},
+	args []string,
+	t reflect.Type,
+	name string) (reflect.Value, error) {
+	elem := reflect.New(t).Elem()
+
+	switch {
+	case v == nil:
+	case t.Kind() == reflect.Interface:
+		if !reflect.TypeOf(v).AssignableTo(t) {
+			return reflect.Value{}, ErrInvalidType
+		}
+		elem.Set(reflect.ValueOf(v))
+	default:
+		structField := reflect.StructField{Name: name, Type: t}
+		value, err := ctx.unmarshalAttribute(v, args, structField, elem)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		assign(elem, value)
+	}
+
+	return elem, nil
+}
+
+// elementError locates err at the map key or slice index token. The
+// resulting errors have pointers relative to the attribute, which
+// unmarshalNode completes.
+func elementError(token string, err error) error {
+	return locateError(pointerTo("", token), "", err)
+}
+
+// locateError prefixes the JSON Pointers of the *FieldError values in err,
+// which are relative to a nested value, with pointer. Other errors are
+// located at pointer itself. field names the struct field of errors that
+// don't have one yet.
+func locateError(pointer, field string, err error) error {
+	switch e := err.(type) {
+	case *FieldError:
+		if e.Field != "" {
+			field = e.Field
+		}
+		return newFieldError(pointer+e.Pointer, field, e.Err)
+	case MultiError:
+		errs := make(MultiError, len(e))
+		for i := range e {
+			errs[i] = locateError(pointer, field, e[i])
+		}
+		return errs
+	}
+
+	return newFieldError(pointer, field, err)
+}
+
+// parseMapKey converts the member name into a map key of type t, as
+// encoding/json does.
+func parseMapKey(name string, t reflect.Type) (reflect.Value, error) {
+	key := reflect.New(t).Elem()
+
+	if t.Kind() != reflect.String && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if err := key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(name)); err != nil {
+			return reflect.Value{}, err
+		}
+		return key, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		key.SetString(name)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(name, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, ErrInvalidType
+		}
+		key.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(name, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, ErrInvalidType
+		}
+		key.SetUint(n)
+	default:
+		return reflect.Value{}, ErrInvalidType
+	}
+
+	return key, nil
 }
@@ -1430,3 +1430,586 @@ func TestUnmarshalNestedStructSlice(t *testing.T) {
 			out.Teams[0].Members[0].Firstname)
 	}
 }
+
+func TestUnmarshalPayload_collectErrors(t *testing.T) {
+	in := map[string]interface{}{
+		"string_field":   0,
+		"float_field":    "A string.",
+		"time_field":     "A string.",
+		"time_ptr_field": "A string.",
+	}
+
+	err := UnmarshalPayload(samplePayloadWithBadTypes(in), new(ModelBadTypes), CollectErrors())
+	if err == nil {
+		t.Fatal("Expected an error due to invalid types")
+	}
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %T", err)
+	}
+	if e, a := 4, len(merr); e != a {
+		t.Fatalf("Was expecting %d errors, got %d: %v", e, a, err)
+	}
+
+	expected := map[string]error{
+		"/data/attributes/string_field":   ErrUnknownFieldNumberType,
+		"/data/attributes/float_field":    ErrInvalidType,
+		"/data/attributes/time_field":     ErrInvalidTime,
+		"/data/attributes/time_ptr_field": ErrInvalidTime,
+	}
+	for _, e := range merr {
+		var ferr *FieldError
+		if !errors.As(e, &ferr) {
+			t.Fatalf("Was expecting a *FieldError, got %T", e)
+		}
+		if want, ok := expected[ferr.Pointer]; !ok || !errors.Is(ferr, want) {
+			t.Fatalf("Unexpected error at %s: %v", ferr.Pointer, ferr.Err)
+		}
+	}
+
+	if !errors.Is(err, ErrInvalidType) {
+		t.Fatal("Was expecting errors.Is to find ErrInvalidType")
+	}
+}
+
+func TestUnmarshalPayload_firstErrorAsIs(t *testing.T) {
+	in := map[string]interface{}{"float_field": "A string."}
+
+	err := UnmarshalPayload(samplePayloadWithBadTypes(in), new(ModelBadTypes))
+	if err != ErrInvalidType {
+		t.Fatalf("Was expecting ErrInvalidType, got %v", err)
+	}
+
+	err = UnmarshalPayload(samplePayloadWithBadTypes(in), new(ModelBadTypes), CollectErrors())
+
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Pointer != "/data/attributes/float_field" || ferr.Field != "FloatField" {
+		t.Fatalf("Was expecting an error at /data/attributes/float_field, got %v", err)
+	}
+}
+
+func TestUnmarshalPayload_collectErrorsInRelationships(t *testing.T) {
+	sample := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "posts",
+			"id":   "1",
+			"attributes": map[string]interface{}{
+				"title": 5,
+			},
+			"relationships": map[string]interface{}{
+				"comments": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "comments", "id": "1"},
+						map[string]interface{}{"type": "comments", "id": "x"},
+					},
+				},
+			},
+		},
+		"included": []interface{}{
+			map[string]interface{}{
+				"type":       "comments",
+				"id":         "1",
+				"attributes": map[string]interface{}{"body": true},
+			},
+		},
+	}
+	data, err := json.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UnmarshalPayload(bytes.NewReader(data), new(Post), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var pointers []string
+	for _, obj := range merr.ErrorObjects() {
+		pointers = append(pointers, obj.Source.Pointer)
+	}
+	sort.Strings(pointers)
+
+	expected := []string{
+		"/data/attributes/title",
+		"/data/relationships/comments/data/1/id",
+		"/included/0/attributes/body",
+	}
+	if !reflect.DeepEqual(expected, pointers) {
+		t.Fatalf("Was expecting pointers %v, got %v", expected, pointers)
+	}
+}
+
+func TestUnmarshalManyPayload_collectErrors(t *testing.T) {
+	sample := map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"type":       "posts",
+				"id":         "1",
+				"attributes": map[string]interface{}{"title": 1},
+			},
+			map[string]interface{}{
+				"type":       "posts",
+				"id":         "2",
+				"attributes": map[string]interface{}{"body": 2},
+			},
+		},
+	}
+	data, err := json.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = UnmarshalManyPayload(bytes.NewReader(data), reflect.TypeOf(new(Post)), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+	if e, a := 2, len(merr); e != a {
+		t.Fatalf("Was expecting %d errors, got %d", e, a)
+	}
+	if e, a := "/data/1/attributes/body", merr[1].(*FieldError).Pointer; e != a {
+		t.Fatalf("Was expecting pointer %s, got %s", e, a)
+	}
+}
+
+func TestUnmarshalPayload_malformedRelationships(t *testing.T) {
+	for name, relationships := range map[string]string{
+		"relationship not an object": `{"latest_comment": [1]}`,
+		"to-one data is an array":    `{"latest_comment": {"data": []}}`,
+		"to-many data is an object":  `{"comments": {"data": {"type": "comments", "id": "1"}}}`,
+		"linkage not an object":      `{"comments": {"data": ["1"]}}`,
+		"null in to-many linkage":    `{"comments": {"data": [null]}}`,
+		"missing type":               `{"latest_comment": {"data": {"id": "1"}}}`,
+		"numeric id":                 `{"latest_comment": {"data": {"type": "comments", "id": 1}}}`,
+		"attributes not an object":   `{"latest_comment": {"data": {"type": "comments", "id": "1", "attributes": 1}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := strings.NewReader(`{"data": {"type": "posts", "id": "1", "relationships": ` + relationships + `}}`)
+
+			err := UnmarshalPayload(in, new(Post))
+			if !errors.Is(err, ErrMalformedRelationship) {
+				t.Fatalf("Was expecting ErrMalformedRelationship, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPayload_malformedRelationshipPointer(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "posts", "id": "1", "relationships": {
+		"comments": {"data": {"type": "comments", "id": "1"}}
+	}}}`)
+
+	err := UnmarshalPayload(in, new(Post), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) || len(merr) != 1 {
+		t.Fatalf("Was expecting a MultiError with one error, got %v", err)
+	}
+	if e, a := "/data/relationships/comments", merr[0].(*FieldError).Pointer; e != a {
+		t.Fatalf("Was expecting pointer %s, got %s", e, a)
+	}
+}
+
+func TestUnmarshalPayload_relationshipWithoutData(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "posts", "id": "1", "relationships": {
+		"comments": {"links": {"related": "/posts/1/comments"}},
+		"latest_comment": {"meta": {"count": 1}}
+	}}}`)
+
+	post := new(Post)
+	if err := UnmarshalPayload(in, post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Comments != nil || post.LatestComment != nil {
+		t.Fatalf("Was expecting relationships without data to be left unset, got %v and %v",
+			post.Comments, post.LatestComment)
+	}
+}
+
+func TestUnmarshalNestedStruct_notAnObject(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "companies", "id": "1", "attributes": {"boss": "Jane"}}}`)
+
+	if err := UnmarshalPayload(in, new(Company)); err != ErrInvalidType {
+		t.Fatalf("Was expecting ErrInvalidType, got %v", err)
+	}
+}
+
+func TestUnmarshalNestedStructSlice_invalidElement(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "companies", "id": "1", "attributes": {
+		"teams": [{"name": "Dev"}, {"name": 1}]
+	}}}`)
+
+	if err := UnmarshalPayload(in, new(Company)); err == nil {
+		t.Fatal("Was expecting an error for the invalid team")
+	}
+}
+
+func TestUnmarshalMapAttributes(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "maps", "id": "1", "attributes": {
+		"counts": {"a": 1, "b": 2},
+		"scores": {"7": 0.5},
+		"labels": {"env": "prod"},
+		"times": {"start": "2016-08-17T08:27:12Z", "end": null},
+		"nested": {"x": {"y": 3}},
+		"extra": {"k": "v"},
+		"any": {"n": 1, "s": ["a"]}
+	}}}`)
+
+	out := new(MapAttributes)
+	if err := UnmarshalPayload(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	expected := &MapAttributes{
+		ID:     "1",
+		Counts: map[string]int{"a": 1, "b": 2},
+		Scores: map[int]float64{7: 0.5},
+		Labels: Labels{"env": "prod"},
+		Times:  map[string]*time.Time{"start": &start, "end": nil},
+		Nested: map[string]map[string]int{"x": {"y": 3}},
+		Extra:  &map[string]string{"k": "v"},
+		Any:    map[string]interface{}{"n": float64(1), "s": []interface{}{"a"}},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("Was expecting %+v, got %+v", expected, out)
+	}
+}
+
+func TestUnmarshalMapAttributes_structValues(t *testing.T) {
+	type Department struct {
+		ID     string               `jsonapi:"primary,departments"`
+		Bosses map[string]*Employee `jsonapi:"attr,bosses"`
+		Teams  map[string]Team      `jsonapi:"attr,teams"`
+	}
+
+	in := strings.NewReader(`{"data": {"type": "departments", "id": "1", "attributes": {
+		"bosses": {"eu": {"firstname": "Jane"}},
+		"teams": {"core": {"name": "Core", "leader": {"firstname": "John"}}}
+	}}}`)
+
+	out := new(Department)
+	if err := UnmarshalPayload(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Bosses["eu"].Firstname != "Jane" {
+		t.Fatalf("Unexpected bosses %+v", out.Bosses)
+	}
+	if team := out.Teams["core"]; team.Name != "Core" || team.Leader.Firstname != "John" {
+		t.Fatalf("Unexpected teams %+v", out.Teams)
+	}
+}
+
+func TestUnmarshalMapAttributes_invalid(t *testing.T) {
+	for name, attributes := range map[string]string{
+		"not an object": `{"counts": [1]}`,
+		"invalid value": `{"counts": {"a": "one"}}`,
+		"invalid key":   `{"scores": {"seven": 0.5}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := strings.NewReader(`{"data": {"type": "maps", "id": "1", "attributes": ` + attributes + `}}`)
+			if err := UnmarshalPayload(in, new(MapAttributes)); err == nil {
+				t.Fatal("Was expecting an error")
+			}
+		})
+	}
+}
+
+func TestUnmarshalSliceAttributes(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "slices", "id": "1", "attributes": {
+		"ints": [1, 2, 3],
+		"scores": [0.5, 1.5],
+		"names": ["a", null],
+		"times": ["2016-08-17T08:27:12Z"],
+		"matrix": [["a", "b"], ["c"]],
+		"point": [1, 2, 3, 4],
+		"data": "aGk=",
+		"members": [{"firstname": "Jane"}, null],
+		"buckets": [{"a": 1}],
+		"ptr": [1, 2],
+		"any": [1, "a", null]
+	}}}`)
+
+	out := new(SliceAttributes)
+	if err := UnmarshalPayload(in, out); err != nil {
+		t.Fatal(err)
+	}
+
+	a := "a"
+	ptr := []uint8{1, 2}
+	expected := &SliceAttributes{
+		ID:      "1",
+		Ints:    []int{1, 2, 3},
+		Scores:  Scores{0.5, 1.5},
+		Names:   []*string{&a, nil},
+		Times:   []time.Time{time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)},
+		Matrix:  [][]string{{"a", "b"}, {"c"}},
+		Point:   [3]int{1, 2, 3},
+		Data:    []byte("hi"),
+		Members: []*Employee{{Firstname: "Jane"}, nil},
+		Buckets: []map[string]int{{"a": 1}},
+		Ptr:     &ptr,
+		Any:     []interface{}{float64(1), "a", nil},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("Was expecting %+v, got %+v", expected, out)
+	}
+}
+
+func TestUnmarshalSliceAttributes_invalidElement(t *testing.T) {
+	for name, tc := range map[string]struct {
+		attributes string
+		pointer    string
+		err        error
+	}{
+		"int": {
+			attributes: `{"ints": [1, "two"]}`,
+			pointer:    "/data/attributes/ints/1",
+			err:        ErrInvalidType,
+		},
+		"nested slice": {
+			attributes: `{"matrix": [["a"], ["b", 1]]}`,
+			pointer:    "/data/attributes/matrix/1/1",
+			err:        ErrUnknownFieldNumberType,
+		},
+		"time": {
+			attributes: `{"times": [1471422432]}`,
+			pointer:    "/data/attributes/times/0",
+			err:        ErrInvalidRFC3339,
+		},
+		"map in slice": {
+			attributes: `{"buckets": [{"a": 1}, {"b": "x"}]}`,
+			pointer:    "/data/attributes/buckets/1/b",
+			err:        ErrInvalidType,
+		},
+		"not an array": {
+			attributes: `{"ints": 1}`,
+			pointer:    "/data/attributes/ints",
+			err:        ErrInvalidType,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := strings.NewReader(`{"data": {"type": "slices", "id": "1", "attributes": ` + tc.attributes + `}}`)
+			err := UnmarshalPayload(in, new(SliceAttributes), CollectErrors())
+
+			var merr MultiError
+			if !errors.As(err, &merr) || len(merr) != 1 {
+				t.Fatalf("Was expecting a single error, got %v", err)
+			}
+			var ferr *FieldError
+			if !errors.As(merr[0], &ferr) || ferr.Pointer != tc.pointer || !errors.Is(ferr, tc.err) {
+				t.Fatalf("Was expecting %v at %s, got %v", tc.err, tc.pointer, merr[0])
+			}
+		})
+	}
+}
+
+func TestUnmarshalNestedStruct_errorPointers(t *testing.T) {
+	for name, tc := range map[string]struct {
+		attributes string
+		pointer    string
+		err        error
+	}{
+		"struct": {
+			attributes: `{"boss": {"hired-at": 5}}`,
+			pointer:    "/data/attributes/boss/hired-at",
+			err:        ErrInvalidISO8601,
+		},
+		"pointer": {
+			attributes: `{"teams": [{"leader": {"age": "old"}}]}`,
+			pointer:    "/data/attributes/teams/0/leader/age",
+			err:        ErrInvalidType,
+		},
+		"slice": {
+			attributes: `{"teams": [{"name": "Core"}, {"members": [{}, {"firstname": 1}]}]}`,
+			pointer:    "/data/attributes/teams/1/members/1/firstname",
+			err:        ErrUnknownFieldNumberType,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := `{"data": {"type": "companies", "id": "1", "attributes": ` + tc.attributes + `}}`
+
+			for _, opts := range [][]Option{nil, {CollectErrors()}} {
+				err := UnmarshalPayload(strings.NewReader(in), new(Company), opts...)
+
+				var ferr *FieldError
+				if !errors.As(err, &ferr) || ferr.Pointer != tc.pointer || !errors.Is(err, tc.err) {
+					t.Fatalf("Was expecting %v at %s, got %v", tc.err, tc.pointer, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalNestedStruct_collectsAllErrors(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "companies", "id": "1", "attributes": {
+		"boss": {"firstname": 1, "age": "old"}
+	}}}`)
+
+	err := UnmarshalPayload(in, new(Company), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) || len(merr) != 2 {
+		t.Fatalf("Was expecting 2 errors, got %v", err)
+	}
+	for i, pointer := range []string{"/data/attributes/boss/firstname", "/data/attributes/boss/age"} {
+		if ferr := merr[i].(*FieldError); ferr.Pointer != pointer {
+			t.Fatalf("Was expecting an error at %s, got %v", pointer, ferr)
+		}
+	}
+}
+
+func TestUnmarshalNestedStruct_jsonTags(t *testing.T) {
+	in := strings.NewReader(`{"data": {"type": "offices", "id": "1", "attributes": {
+		"address": {"street": "Main Street", "city": "Amsterdam"},
+		"staff": {"cto": {"firstname": "Jane", "hired-at": "2016-08-17T08:27:12Z"}}
+	}}}`)
+
+	out := new(Office)
+	if err := UnmarshalPayload(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Address != (Address{Street: "Main Street", City: "Amsterdam"}) {
+		t.Fatalf("Unexpected address %+v", out.Address)
+	}
+	if cto := out.Staff["cto"]; cto.Firstname != "Jane" || cto.HiredAt == nil {
+		t.Fatalf("Unexpected staff %+v", out.Staff)
+	}
+}
+
+func TestUnmarshalIdentifierRelations(t *testing.T) {
+	doc := `{"data": {"type": "notes", "id": "1", "relationships": {
+		"author": {"data": {"type": "people", "id": "7"}},
+		"editor": {"data": {"type": "people", "id": "jane"}},
+		"tags": {"data": [{"type": "tags", "id": "a"}, {"type": "tags", "id": "b"}]},
+		"shop": {"data": {"type": "shops", "id": "123e4567-e89b-12d3-a456-426655440000"}}
+	}}}`
+
+	note := new(Note)
+	if err := UnmarshalPayload(strings.NewReader(doc), note); err != nil {
+		t.Fatal(err)
+	}
+
+	editor := "jane"
+	expected := &Note{
+		ID:       1,
+		AuthorID: 7,
+		EditorID: &editor,
+		TagIDs:   []string{"a", "b"},
+		ShopID:   &UUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x55, 0x44, 0, 0},
+	}
+	if !reflect.DeepEqual(note, expected) {
+		t.Fatalf("Was expecting %+v, got %+v", expected, note)
+	}
+}
+
+func TestUnmarshalIdentifierRelations_errors(t *testing.T) {
+	doc := `{"data": {"type": "notes", "relationships": {
+		"author": {"data": {"type": "authors", "id": "7"}},
+		"tags": {"data": [{"type": "tags", "id": "a"}, {"type": "labels", "id": "b"}]},
+		"shop": {"data": {"type": "shops", "id": "acme"}}
+	}}}`
+
+	err := UnmarshalPayload(strings.NewReader(doc), new(Note), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var pointers []string
+	for _, obj := range merr.ErrorObjects() {
+		pointers = append(pointers, obj.Source.Pointer)
+	}
+	expected := []string{
+		"/data/relationships/author/data/type",
+		"/data/relationships/tags/data/1/type",
+		"/data/relationships/shop/data/id",
+	}
+	if !reflect.DeepEqual(pointers, expected) {
+		t.Fatalf("Was expecting errors at %v, got %v", expected, pointers)
+	}
+}
+
+func TestUnmarshalPayload_invalidModel(t *testing.T) {
+	doc := `{"data": {"type": "blogs", "id": "1"}}`
+
+	var nilBlog *Blog
+	for name, tc := range map[string]struct {
+		model    interface{}
+		expected string
+	}{
+		"nil":         {nil, "jsonapi: Unmarshal(nil)"},
+		"non-pointer": {Blog{}, "jsonapi: Unmarshal(non-pointer jsonapi.Blog)"},
+		"non-struct":  {new(string), "jsonapi: Unmarshal(pointer to non-struct *string)"},
+		"nil pointer": {nilBlog, "jsonapi: Unmarshal(nil *jsonapi.Blog)"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := UnmarshalPayload(strings.NewReader(doc), tc.model)
+
+			var ierr *InvalidUnmarshalError
+			if !errors.As(err, &ierr) || err.Error() != tc.expected {
+				t.Fatalf("Was expecting %q, got %v", tc.expected, err)
+			}
+
+			err = NewDecoder(strings.NewReader(doc)).Decode(tc.model)
+			if !errors.As(err, &ierr) {
+				t.Fatalf("Was expecting an *InvalidUnmarshalError from the Decoder, got %v", err)
+			}
+		})
+	}
+
+	_, err := UnmarshalManyPayload(strings.NewReader(`{"data": []}`), reflect.TypeOf(Blog{}))
+	var ierr *InvalidUnmarshalError
+	if !errors.As(err, &ierr) {
+		t.Fatalf("Was expecting an *InvalidUnmarshalError, got %v", err)
+	}
+}
+
+func TestUnmarshalPayload_nullData(t *testing.T) {
+	if err := UnmarshalPayload(strings.NewReader(`{"data": null}`), new(Blog)); err != ErrNullData {
+		t.Fatalf("Was expecting ErrNullData, got %v", err)
+	}
+
+	_, err := UnmarshalManyPayload(strings.NewReader(`{"data": [null]}`), reflect.TypeOf(new(Blog)))
+	if err != ErrNullData {
+		t.Fatalf("Was expecting ErrNullData, got %v", err)
+	}
+
+	doc := `{"data": {"type": "blogs", "id": "1"}, "included": [null]}`
+	if err := UnmarshalPayload(strings.NewReader(doc), new(Blog)); err != nil {
+		t.Fatalf("Was expecting null included resources to be ignored, got %v", err)
+	}
+}
+
+func TestUnmarshalPayload_invalidFields(t *testing.T) {
+	type Unexported struct {
+		ID    int    `jsonapi:"primary,unexported"`
+		title string `jsonapi:"attr,title"`
+	}
+	type IntClientID struct {
+		ID       int `jsonapi:"primary,int-client-ids"`
+		ClientID int `jsonapi:"client-id"`
+	}
+
+	for name, tc := range map[string]struct {
+		model interface{}
+		field string
+	}{
+		"unexported": {new(Unexported), "title"},
+		"client-id":  {new(IntClientID), "ClientID"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := UnmarshalPayload(strings.NewReader(`{"data": {"type": "x", "client-id": "1", "attributes": {"title": "T"}}}`), tc.model)
+
+			var terr *TagError
+			if !errors.As(err, &terr) || terr.Field != tc.field {
+				t.Fatalf("Was expecting a *TagError for %s, got %v", tc.field, err)
+			}
+		})
+	}
+}
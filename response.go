@@ -15,13 +15,13 @@
 package jsonapi
 
 import (
-	"encoding/json"
+	"encoding"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -30,7 +30,8 @@ var (
 	// annotation is invalid.
 	ErrBadJSONAPIStructTag = errors.New("Bad jsonapi struct tag format")
 	// ErrBadJSONAPIID is returned when the Struct JSON API annotated "id" field
-	// was not a valid numeric type.
+	// was not a string or a valid numeric type, and did not implement IDMarshaler
+	// or encoding.TextMarshaler, or when its id could not be formatted or parsed.
 	ErrBadJSONAPIID = errors.New(
 		"id should be either string, int(8,16,32,64) or uint(8,16,32,64)")
 	// ErrExpectedSlice is returned when a variable or argument was expected to
@@ -75,19 +76,22 @@ var (
 //				 http.Error(w, err.Error(), http.StatusInternalServerError)
 //			 }
 //		 }
-func MarshalPayload(w io.Writer, models interface{}) error {
-	payload, err := Marshal(models)
-	if err != nil {
-		return err
-	}
-
-	return json.NewEncoder(w).Encode(payload)
+//
+// MarshalPayload writes the JSON straight from the models into a pooled
+// buffer, without building the Node tree Marshal returns; the output is the
+// same as encoding that tree. Use an Encoder to change the formatting.
+func MarshalPayload(w io.Writer, models interface{}, opts ...Option) error {
+	return NewEncoder(w, opts...).Encode(models)
 }
 
 // Marshal does the same as MarshalPayload except it just returns the payload
 // and doesn't write out results. Useful if you use your own JSON rendering
 // library.
-func Marshal(models interface{}) (Payloader, error) {
+func Marshal(models interface{}, opts ...Option) (Payloader, error) {
+	return marshal(models, newOptions(opts))
+}
+
+func marshal(models interface{}, o *options) (Payloader, error) {
 	switch vals := reflect.ValueOf(models); vals.Kind() {
 	case reflect.Slice:
 		m, err := convertToSliceInterface(&models)
@@ -95,7 +99,7 @@ func Marshal(models interface{}) (Payloader, error) {
 			return nil, err
 		}
 
-		payload, err := marshalMany(m)
+		payload, err := marshalMany(m, o)
 		if err != nil {
 			return nil, err
 		}
@@ -118,7 +122,7 @@ func Marshal(models interface{}) (Payloader, error) {
 		if reflect.Indirect(vals).Kind() != reflect.Struct {
 			return nil, ErrUnexpectedType
 		}
-		return marshalOne(models)
+		return marshalOne(models, o)
 	default:
 		return nil, ErrUnexpectedType
 	}
@@ -131,23 +135,17 @@ func Marshal(models interface{}) (Payloader, error) {
 //
 // models interface{} should be either a struct pointer or a slice of struct
 // pointers.
-func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}) error {
-	payload, err := Marshal(model)
-	if err != nil {
-		return err
-	}
-	payload.clearIncluded()
-
-	return json.NewEncoder(w).Encode(payload)
+func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}, opts ...Option) error {
+	return NewEncoder(w, opts...).EncodeWithoutIncluded(model)
 }
 
 // marshalOne does the same as MarshalOnePayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalOne(model interface{}) (*OnePayload, error) {
+func marshalOne(model interface{}, o *options) (*OnePayload, error) {
 	included := make(map[string]*Node)
 
-	rootNode, err := visitModelNode(model, &included, true)
+	rootNode, err := visitModelNode(model, &included, true, o)
 	if err != nil {
 		return nil, err
 	}
@@ -161,14 +159,14 @@ func marshalOne(model interface{}) (*OnePayload, error) {
 // marshalMany does the same as MarshalManyPayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalMany(models []interface{}) (*ManyPayload, error) {
+func marshalMany(models []interface{}, o *options) (*ManyPayload, error) {
 	payload := &ManyPayload{
 		Data: []*Node{},
 	}
 	included := map[string]*Node{}
 
 	for _, model := range models {
-		node, err := visitModelNode(model, &included, true)
+		node, err := visitModelNode(model, &included, true, o)
 		if err != nil {
 			return nil, err
 		}
@@ -194,19 +192,12 @@ func marshalMany(models []interface{}) (*ManyPayload, error) {
 // this method is intended for.
 //
 // model interface{} should be a pointer to a struct.
-func MarshalOnePayloadEmbedded(w io.Writer, model interface{}) error {
-	rootNode, err := visitModelNode(model, nil, false)
-	if err != nil {
-		return err
-	}
-
-	payload := &OnePayload{Data: rootNode}
-
-	return json.NewEncoder(w).Encode(payload)
+func MarshalOnePayloadEmbedded(w io.Writer, model interface{}, opts ...Option) error {
+	return NewEncoder(w, opts...).EncodeEmbedded(model)
 }
 
 func visitModelNode(model interface{}, included *map[string]*Node,
-	sideload bool) (*Node, error) {
+	sideload bool, o *options) (*Node, error) {
 	node := new(Node)
 
 	var er error
@@ -218,73 +209,23 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 	modelValue := value.Elem()
 	modelType := value.Type().Elem()
 
-	for i := 0; i < modelValue.NumField(); i++ {
-		structField := modelValue.Type().Field(i)
-		tag := structField.Tag.Get(annotationJSONAPI)
-		if tag == "" {
+	info, err := getModelInfo(modelType, o.naming)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range info.fields {
+		if field.access == accessWriteOnly {
 			continue
 		}
 
-		fieldValue := modelValue.Field(i)
-		fieldType := modelType.Field(i)
-
-		args := strings.Split(tag, annotationSeperator)
+		fieldValue := modelValue.Field(field.index)
 
-		if len(args) < 1 {
-			er = ErrBadJSONAPIStructTag
-			break
-		}
-
-		annotation := args[0]
-
-		if (annotation == annotationClientID && len(args) != 1) ||
-			(annotation != annotationClientID && len(args) < 2) {
-			er = ErrBadJSONAPIStructTag
-			break
-		}
+		args := field.args
+		annotation := field.annotation
 
 		if annotation == annotationPrimary {
-			v := fieldValue
-
-			// Deal with PTRS
-			var kind reflect.Kind
-			if fieldValue.Kind() == reflect.Ptr {
-				kind = fieldType.Type.Elem().Kind()
-				v = reflect.Indirect(fieldValue)
-			} else {
-				kind = fieldType.Type.Kind()
-			}
-
-			// Handle allowed types
-			switch kind {
-			case reflect.String:
-				node.ID = v.Interface().(string)
-			case reflect.Int:
-				node.ID = strconv.FormatInt(int64(v.Interface().(int)), 10)
-			case reflect.Int8:
-				node.ID = strconv.FormatInt(int64(v.Interface().(int8)), 10)
-			case reflect.Int16:
-				node.ID = strconv.FormatInt(int64(v.Interface().(int16)), 10)
-			case reflect.Int32:
-				node.ID = strconv.FormatInt(int64(v.Interface().(int32)), 10)
-			case reflect.Int64:
-				node.ID = strconv.FormatInt(v.Interface().(int64), 10)
-			case reflect.Uint:
-				node.ID = strconv.FormatUint(uint64(v.Interface().(uint)), 10)
-			case reflect.Uint8:
-				node.ID = strconv.FormatUint(uint64(v.Interface().(uint8)), 10)
-			case reflect.Uint16:
-				node.ID = strconv.FormatUint(uint64(v.Interface().(uint16)), 10)
-			case reflect.Uint32:
-				node.ID = strconv.FormatUint(uint64(v.Interface().(uint32)), 10)
-			case reflect.Uint64:
-				node.ID = strconv.FormatUint(v.Interface().(uint64), 10)
-			default:
-				// We had a JSON float (numeric), but our field was not one of the
-				// allowed numeric types
-				er = ErrBadJSONAPIID
-			}
-
+			node.ID, er = formatID(fieldValue)
 			if er != nil {
 				break
 			}
@@ -296,90 +237,26 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				node.ClientID = clientID
 			}
 		} else if annotation == annotationAttribute {
-			var omitEmpty, iso8601, rfc3339 bool
-
-			if len(args) > 2 {
-				for _, arg := range args[2:] {
-					switch arg {
-					case annotationOmitEmpty:
-						omitEmpty = true
-					case annotationISO8601:
-						iso8601 = true
-					case annotationRFC3339:
-						rfc3339 = true
-					}
-				}
-			}
-
 			if node.Attributes == nil {
 				node.Attributes = make(map[string]interface{})
 			}
 
-			if fieldValue.Type() == reflect.TypeOf(time.Time{}) {
-				t := fieldValue.Interface().(time.Time)
-
-				if t.IsZero() {
-					continue
-				}
-
-				if iso8601 {
-					node.Attributes[args[1]] = t.UTC().Format(iso8601TimeFormat)
-				} else if rfc3339 {
-					node.Attributes[args[1]] = t.UTC().Format(time.RFC3339)
-				} else {
-					node.Attributes[args[1]] = t.Unix()
-				}
-			} else if fieldValue.Type() == reflect.TypeOf(new(time.Time)) {
-				// A time pointer may be nil
-				if fieldValue.IsNil() {
-					if omitEmpty {
-						continue
-					}
-
-					node.Attributes[args[1]] = nil
-				} else {
-					tm := fieldValue.Interface().(*time.Time)
-
-					if tm.IsZero() && omitEmpty {
-						continue
-					}
-
-					if iso8601 {
-						node.Attributes[args[1]] = tm.UTC().Format(iso8601TimeFormat)
-					} else if rfc3339 {
-						node.Attributes[args[1]] = tm.UTC().Format(time.RFC3339)
-					} else {
-						node.Attributes[args[1]] = tm.Unix()
-					}
-				}
-			} else {
-				// Dealing with a fieldValue that is not a time
-				emptyValue := reflect.Zero(fieldValue.Type())
-
-				// See if we need to omit this field
-				if omitEmpty && reflect.DeepEqual(fieldValue.Interface(), emptyValue.Interface()) {
-					continue
-				}
-
-				strAttr, ok := fieldValue.Interface().(string)
-				if ok {
-					node.Attributes[args[1]] = strAttr
-				} else {
-					node.Attributes[args[1]] = fieldValue.Interface()
-				}
+			value, ok, err := attribute(fieldValue, field, o)
+			if err != nil {
+				er = err
+				break
+			}
+			if ok {
+				node.Attributes[args[1]] = value
 			}
 		} else if annotation == annotationRelation {
-			var omitEmpty bool
-
 			//add support for 'omitempty' struct tag for marshaling as absent
-			if len(args) > 2 {
-				omitEmpty = args[2] == annotationOmitEmpty
-			}
+			omitEmpty := field.hasOption(annotationOmitEmpty)
 
 			isSlice := fieldValue.Type().Kind() == reflect.Slice
 			if omitEmpty &&
 				(isSlice && fieldValue.Len() < 1 ||
-					(!isSlice && fieldValue.IsNil())) {
+					(!isSlice && isNullRelation(fieldValue))) {
 				continue
 			}
 
@@ -397,12 +274,24 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				relMeta = metableModel.JSONAPIRelationshipMeta(args[1])
 			}
 
+			if field.linkageType != "" {
+				// identifier-only relationship
+				relationship, err := identifierRelationship(fieldValue, field.linkageType, relLinks, relMeta)
+				if err != nil {
+					er = err
+					break
+				}
+				node.Relationships[args[1]] = relationship
+				continue
+			}
+
 			if isSlice {
 				// to-many relationship
 				relationship, err := visitModelNodeRelationships(
 					fieldValue,
 					included,
 					sideload,
+					o,
 				)
 				if err != nil {
 					er = err
@@ -430,15 +319,16 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				// to-one relationships
 
 				// Handle null relationship case
-				if fieldValue.IsNil() {
+				if isNullRelation(fieldValue) {
 					node.Relationships[args[1]] = &RelationshipOneNode{Data: nil}
 					continue
 				}
 
 				relationship, err := visitModelNode(
-					fieldValue.Interface(),
+					relatedModel(fieldValue).Interface(),
 					included,
 					sideload,
+					o,
 				)
 				if err != nil {
 					er = err
@@ -461,9 +351,6 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 				}
 			}
 
-		} else {
-			er = ErrBadJSONAPIStructTag
-			break
 		}
 	}
 
@@ -486,6 +373,248 @@ func visitModelNode(model interface{}, included *map[string]*Node,
 	return node, nil
 }
 
+// attribute returns the value of the attribute field of a model, and whether
+// it is present.
+func attribute(fieldValue reflect.Value, field *fieldInfo, o *options) (interface{}, bool, error) {
+	var omitEmpty bool
+
+	if len(field.args) > 2 {
+		for _, arg := range field.args[2:] {
+			if arg == annotationOmitEmpty {
+				omitEmpty = true
+			}
+		}
+	}
+	format := parseTimeFormat(field.args)
+
+	if t := fieldValue.Type(); isTimeType(t) {
+		tm := timeValue(fieldValue)
+
+		if tm.IsZero() {
+			return nil, false, nil
+		}
+
+		return format.format(tm), true, nil
+	} else if t.Kind() == reflect.Ptr && isTimeType(t.Elem()) {
+		// A time pointer may be nil
+		if fieldValue.IsNil() {
+			if omitEmpty {
+				return nil, false, nil
+			}
+
+			return nil, true, nil
+		}
+
+		tm := timeValue(fieldValue.Elem())
+
+		if tm.IsZero() && omitEmpty {
+			return nil, false, nil
+		}
+
+		return format.format(tm), true, nil
+	}
+
+	// Dealing with a fieldValue that is not a time
+	emptyValue := reflect.Zero(fieldValue.Type())
+
+	// See if we need to omit this field
+	if omitEmpty && reflect.DeepEqual(fieldValue.Interface(), emptyValue.Interface()) {
+		return nil, false, nil
+	}
+
+	if strAttr, ok := fieldValue.Interface().(string); ok {
+		return strAttr, true, nil
+	}
+	if field.composite {
+		v, err := attributeValue(fieldValue, format, o)
+		return v, err == nil, err
+	}
+
+	return fieldValue.Interface(), true, nil
+}
+
+// attributeValue returns the value of a composite attribute, a nested struct,
+// map, slice or array, as visitModelNode stores it: nested structs with
+// jsonapi tags become objects of their attributes, map keys are formatted as
+// encoding/json formats them, nullable database/sql values are null or their
+// value and times and durations, at any depth, are formatted according to the
+// time options of the attribute.
+func attributeValue(v reflect.Value, format timeFormat, o *options) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+
+	switch t := v.Type(); {
+	case isTimeType(t):
+		return format.format(timeValue(v)), nil
+	case t == durationType:
+		return format.formatDuration(time.Duration(v.Int())), nil
+	}
+	if index, ok := nullValueIndex(v.Type()); ok {
+		value, valid := nullValue(v, index)
+		if !valid {
+			return nil, nil
+		}
+		return attributeValue(value, format, o)
+	}
+	if isMarshaler(v.Type()) {
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return attributeValue(v.Elem(), format, o)
+	case reflect.Struct:
+		if !hasJSONAPITags(v.Type()) {
+			return v.Interface(), nil
+		}
+		return nestedAttributes(v, o)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := formatMapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = attributeValue(iter.Value(), format, o); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Encoded as a base64 string.
+			return v.Interface(), nil
+		}
+		fallthrough
+	case reflect.Array:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			var err error
+			if s[i], err = attributeValue(v.Index(i), format, o); err != nil {
+				return nil, err
+			}
+		}
+
+		return s, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// nestedAttributes returns the object of the attributes of v, a nested struct
+// with jsonapi tags. Its attributes are written as those of a resource, with
+// their own tag options.
+func nestedAttributes(v reflect.Value, o *options) (interface{}, error) {
+	info, err := getModelInfo(v.Type(), o.naming)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{}, len(info.fields))
+	for _, field := range info.fields {
+		if field.annotation != annotationAttribute || field.access == accessWriteOnly {
+			continue
+		}
+
+		value, ok, err := attribute(v.Field(field.index), field, o)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			attributes[field.name] = value
+		}
+	}
+
+	return attributes, nil
+}
+
+// hasJSONAPITags reports whether the struct type t has fields with a jsonapi
+// tag. Nested structs without them are plain JSON values, encoded and decoded
+// with encoding/json and their json tags.
+func hasJSONAPITags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup(annotationJSONAPI); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isComposite reports whether attributes of type t must be converted by
+// attributeValue before encoding/json encodes them, because they hold nested
+// structs with jsonapi tags, maps, nullable database/sql values, durations, or
+// times within slices or arrays.
+func isComposite(t reflect.Type) bool {
+	return hasConvertedValues(t, map[reflect.Type]bool{})
+}
+
+func hasConvertedValues(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if isTimeType(t) || t == durationType {
+		return true
+	}
+	if _, ok := nullValueIndex(t); ok {
+		return true
+	}
+	if isMarshaler(t) || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return hasJSONAPITags(t)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return hasConvertedValues(t.Elem(), seen)
+	}
+
+	return false
+}
+
+// isMarshaler reports whether values of type t encode themselves.
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// formatMapKey returns the member name of the map key k, as encoding/json
+// formats it.
+func formatMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+
+	return "", fmt.Errorf("jsonapi: unsupported map key type %v", k.Type())
+}
+
 func toShallowNode(node *Node) *Node {
 	return &Node{
 		ID:   node.ID,
@@ -494,13 +623,18 @@ func toShallowNode(node *Node) *Node {
 }
 
 func visitModelNodeRelationships(models reflect.Value, included *map[string]*Node,
-	sideload bool) (*RelationshipManyNode, error) {
+	sideload bool, o *options) (*RelationshipManyNode, error) {
 	nodes := []*Node{}
 
 	for i := 0; i < models.Len(); i++ {
-		n := models.Index(i).Interface()
+		elem := models.Index(i)
+		if elem.Kind() == reflect.Ptr && elem.IsNil() {
+			// A nil related model has no resource object to link to
+			continue
+		}
+		n := relatedModel(elem).Interface()
 
-		node, err := visitModelNode(n, included, sideload)
+		node, err := visitModelNode(n, included, sideload, o)
 		if err != nil {
 			return nil, err
 		}
@@ -511,6 +645,36 @@ func visitModelNodeRelationships(models reflect.Value, included *map[string]*Nod
 	return &RelationshipManyNode{Data: nodes}, nil
 }
 
+// identifierRelationship returns the relationship object of an
+// identifier-only relation, whose field v holds the ids of the related
+// resources of type typ. They are not included.
+func identifierRelationship(v reflect.Value, typ string, links *Links, meta *Meta) (interface{}, error) {
+	if v.Kind() != reflect.Slice {
+		// Handle null relationship case
+		if isNullRelation(v) {
+			return &RelationshipOneNode{Data: nil}, nil
+		}
+
+		id, err := formatID(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RelationshipOneNode{Data: &Node{Type: typ, ID: id}, Links: links, Meta: meta}, nil
+	}
+
+	nodes := make([]*Node, v.Len())
+	for i := range nodes {
+		id, err := formatID(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = &Node{Type: typ, ID: id}
+	}
+
+	return &RelationshipManyNode{Data: nodes, Links: links, Meta: meta}, nil
+}
+
 func appendIncluded(m *map[string]*Node, nodes ...*Node) {
 	included := *m
 
@@ -525,14 +689,19 @@ func appendIncluded(m *map[string]*Node, nodes ...*Node) {
 	}
 }
 
+// nodeMapValues returns the nodes of m ordered by their "type,id" key, so
+// that the "included" array is the same on every run.
 func nodeMapValues(m *map[string]*Node) []*Node {
 	mp := *m
-	nodes := make([]*Node, len(mp))
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	i := 0
-	for _, n := range mp {
-		nodes[i] = n
-		i++
+	nodes := make([]*Node, len(keys))
+	for i, k := range keys {
+		nodes[i] = mp[k]
 	}
 
 	return nodes
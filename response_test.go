@@ -17,9 +17,11 @@ package jsonapi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -1037,3 +1039,230 @@ func testBlog() *Blog {
 		},
 	}
 }
+
+func TestMarshalMapAttributes(t *testing.T) {
+	start := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	model := &MapAttributes{
+		ID:     "1",
+		Counts: map[string]int{"b": 2, "a": 1},
+		Scores: map[int]float64{7: 0.5},
+		Labels: Labels{"env": "prod"},
+		Times:  map[string]*time.Time{"start": &start, "end": nil},
+		Nested: map[string]map[string]int{"x": {"y": 3}},
+		Any:    map[string]interface{}{"n": float64(1)},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, model); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `"attributes":{"any":{"n":1},"counts":{"a":1,"b":2},"labels":{"env":"prod"},` +
+		`"nested":{"x":{"y":3}},"scores":{"7":0.5},"times":{"end":null,"start":"2016-08-17T08:27:12Z"}}`
+	if !strings.Contains(out.String(), expected) {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	roundTrip := new(MapAttributes)
+	if err := UnmarshalPayload(out, roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, model) {
+		t.Fatalf("Was expecting %+v, got %+v", model, roundTrip)
+	}
+}
+
+func TestMarshalSliceAttributes(t *testing.T) {
+	a := "a"
+	ptr := []uint8{1, 2}
+	model := &SliceAttributes{
+		ID:      "1",
+		Ints:    []int{1, 2, 3},
+		Scores:  Scores{0.5, 1.5},
+		Names:   []*string{&a, nil},
+		Times:   []time.Time{time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)},
+		Matrix:  [][]string{{"a", "b"}, {"c"}},
+		Point:   [3]int{1, 2, 3},
+		Data:    []byte("hi"),
+		Buckets: []map[string]int{{"a": 1}},
+		Ptr:     &ptr,
+		Any:     []interface{}{float64(1), "a", nil},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, model); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"times":["2016-08-17T08:27:12Z"]`; !strings.Contains(out.String(), expected) {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	roundTrip := new(SliceAttributes)
+	if err := UnmarshalPayload(out, roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, model) {
+		t.Fatalf("Was expecting %+v, got %+v", model, roundTrip)
+	}
+}
+
+func TestMarshalNestedStructs(t *testing.T) {
+	hiredAt := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	office := &Office{
+		ID:      "1",
+		Address: Address{Street: "Main Street"},
+		Staff: map[string]Employee{
+			"cto": {Firstname: "Jane", HiredAt: &hiredAt},
+		},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, office); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `"attributes":{"address":{"street":"Main Street"},` +
+		`"staff":{"cto":{"age":0,"firstname":"Jane","hired-at":"2016-08-17T08:27:12Z","surname":""}}}`
+	if !strings.Contains(out.String(), expected) {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	roundTrip := new(Office)
+	if err := UnmarshalPayload(out, roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, office) {
+		t.Fatalf("Was expecting %+v, got %+v", office, roundTrip)
+	}
+}
+
+func TestMarshalNestedStructs_roundTrip(t *testing.T) {
+	hiredAt := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	company := &Company{
+		ID:   "1",
+		Name: "Acme",
+		Boss: Employee{Firstname: "Jane", Age: 40, HiredAt: &hiredAt},
+		Teams: []Team{
+			{Name: "Core", Leader: &Employee{Firstname: "John"}, Members: []Employee{{Firstname: "Iz"}}},
+		},
+		FoundedAt: hiredAt,
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, company); err != nil {
+		t.Fatal(err)
+	}
+
+	roundTrip := new(Company)
+	if err := UnmarshalPayload(out, roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, company) {
+		t.Fatalf("Was expecting %+v, got %+v", company, roundTrip)
+	}
+}
+
+func testArticle() *Article {
+	return &Article{
+		ID:      1,
+		Title:   "Relations",
+		Lead:    Author{ID: 2, Name: "Jane"},
+		Authors: Authors{{ID: 2, Name: "Jane"}, {ID: 3, Name: "John"}},
+	}
+}
+
+func TestMarshalValueRelations(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testArticle()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"data":{"type":"articles","id":"1","attributes":{"title":"Relations"},"relationships":{` +
+		`"authors":{"data":[{"type":"authors","id":"2"},{"type":"authors","id":"3"}]},` +
+		`"lead":{"data":{"type":"authors","id":"2"}},` +
+		`"translator":{"data":null}}},` +
+		`"included":[{"type":"authors","id":"2","attributes":{"name":"Jane"}},` +
+		`{"type":"authors","id":"3","attributes":{"name":"John"}}]}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	nodeTree := bytes.NewBuffer(nil)
+	if err := marshalNodeTree(nodeTree, testArticle()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), nodeTree.Bytes()) {
+		t.Fatalf("output differs from the Node tree:\n%s\n%s", out, nodeTree)
+	}
+
+	roundTrip := new(Article)
+	if err := UnmarshalPayload(out, roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, testArticle()) {
+		t.Fatalf("Was expecting %+v, got %+v", testArticle(), roundTrip)
+	}
+}
+
+func TestMarshalUnsupportedRelation(t *testing.T) {
+	type Unsupported struct {
+		ID   int            `jsonapi:"primary,unsupported"`
+		Tags map[string]int `jsonapi:"relation,tags"`
+	}
+
+	for name, run := range map[string]func() error{
+		"marshal": func() error {
+			return MarshalPayload(bytes.NewBuffer(nil), &Unsupported{ID: 1})
+		},
+		"unmarshal": func() error {
+			doc := `{"data": {"type": "unsupported", "relationships": {"tags": {"data": []}}}}`
+			return UnmarshalPayload(strings.NewReader(doc), new(Unsupported))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := run()
+
+			var terr *TagError
+			if !errors.As(err, &terr) || terr.Field != "Tags" || !errors.Is(err, ErrUnsupportedRelation) {
+				t.Fatalf("Was expecting a *TagError wrapping ErrUnsupportedRelation, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMarshalIdentifierRelations(t *testing.T) {
+	note := &Note{ID: 1, Body: "Hello", AuthorID: 7, TagIDs: []string{"a", "b"}}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, note); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"data":{"type":"notes","id":"1","attributes":{"body":"Hello"},"relationships":{` +
+		`"author":{"data":{"type":"people","id":"7"}},` +
+		`"shop":{"data":null},` +
+		`"tags":{"data":[{"type":"tags","id":"a"},{"type":"tags","id":"b"}]}}}}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Was expecting %s, got %s", expected, out)
+	}
+
+	nodeTree := bytes.NewBuffer(nil)
+	if err := marshalNodeTree(nodeTree, note); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), nodeTree.Bytes()) {
+		t.Fatalf("output differs from the Node tree:\n%s\n%s", out, nodeTree)
+	}
+}
+
+func TestMarshalIdentifierRelations_unsupported(t *testing.T) {
+	type Unsupported struct {
+		ID     int     `jsonapi:"primary,unsupported"`
+		Author *Author `jsonapi:"relation,author,type=authors"`
+	}
+
+	err := MarshalPayload(bytes.NewBuffer(nil), &Unsupported{ID: 1})
+	if !errors.Is(err, ErrUnsupportedRelation) {
+		t.Fatalf("Was expecting ErrUnsupportedRelation, got %v", err)
+	}
+}
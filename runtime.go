@@ -48,7 +48,8 @@ const (
 // deserialization but also has a ctx, a map[string]interface{} for storing
 // state, designed for instrumenting serialization timings.
 type Runtime struct {
-	ctx map[string]interface{}
+	ctx  map[string]interface{}
+	opts []Option
 }
 
 // Events is the func type that provides the callback for handling event timings.
@@ -59,7 +60,7 @@ type Events func(*Runtime, Event, string, time.Duration)
 var Instrumentation Events
 
 // NewRuntime creates a Runtime for use in an application.
-func NewRuntime() *Runtime { return &Runtime{make(map[string]interface{})} }
+func NewRuntime() *Runtime { return &Runtime{ctx: make(map[string]interface{})} }
 
 // WithValue adds custom state variables to the runtime context.
 func (r *Runtime) WithValue(key string, value interface{}) *Runtime {
@@ -73,6 +74,23 @@ func (r *Runtime) Value(key string) interface{} {
 	return r.ctx[key]
 }
 
+// WithOptions sets options, such as WithCodec, that apply to every call of
+// the runtime. Options passed to a call are applied after them.
+func (r *Runtime) WithOptions(opts ...Option) *Runtime {
+	r.opts = append(r.opts, opts...)
+
+	return r
+}
+
+// options returns the runtime options followed by opts.
+func (r *Runtime) options(opts []Option) []Option {
+	if len(r.opts) == 0 {
+		return opts
+	}
+
+	return append(append([]Option{}, r.opts...), opts...)
+}
+
 // Instrument is deprecated.
 func (r *Runtime) Instrument(key string) *Runtime {
 	return r.WithValue("instrument", key)
@@ -83,16 +101,16 @@ func (r *Runtime) shouldInstrument() bool {
 }
 
 // UnmarshalPayload has docs in request.go for UnmarshalPayload.
-func (r *Runtime) UnmarshalPayload(reader io.Reader, model interface{}) error {
+func (r *Runtime) UnmarshalPayload(reader io.Reader, model interface{}, opts ...Option) error {
 	return r.instrumentCall(UnmarshalStart, UnmarshalStop, func() error {
-		return UnmarshalPayload(reader, model)
+		return UnmarshalPayload(reader, model, r.options(opts)...)
 	})
 }
 
 // UnmarshalManyPayload has docs in request.go for UnmarshalManyPayload.
-func (r *Runtime) UnmarshalManyPayload(reader io.Reader, kind reflect.Type) (elems []interface{}, err error) {
+func (r *Runtime) UnmarshalManyPayload(reader io.Reader, kind reflect.Type, opts ...Option) (elems []interface{}, err error) {
 	r.instrumentCall(UnmarshalStart, UnmarshalStop, func() error {
-		elems, err = UnmarshalManyPayload(reader, kind)
+		elems, err = UnmarshalManyPayload(reader, kind, r.options(opts)...)
 		return err
 	})
 
@@ -100,9 +118,9 @@ func (r *Runtime) UnmarshalManyPayload(reader io.Reader, kind reflect.Type) (ele
 }
 
 // MarshalPayload has docs in response.go for MarshalPayload.
-func (r *Runtime) MarshalPayload(w io.Writer, model interface{}) error {
+func (r *Runtime) MarshalPayload(w io.Writer, model interface{}, opts ...Option) error {
 	return r.instrumentCall(MarshalStart, MarshalStop, func() error {
-		return MarshalPayload(w, model)
+		return MarshalPayload(w, model, r.options(opts)...)
 	})
 }
 
@@ -0,0 +1,656 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schemaDialect is the JSON Schema version of the generated schemas.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, ready to be encoded with encoding/json.
+type Schema map[string]interface{}
+
+// Schemas holds the JSON Schemas of the documents exchanged for a resource
+// type. Each schema is self-contained: the resource objects it refers to are
+// defined under its "$defs".
+type Schemas struct {
+	// Request is the schema of create request documents. Members tagged
+	// readonly are left out. The built-in jsonapi-validate rules are
+	// described, members with the required rule being required and not
+	// null.
+	Request Schema
+
+	// UpdateRequest is the schema of update request documents, whose
+	// resource must have an id. Members tagged createonly are left out as
+	// well, and no member is required, since update requests only send the
+	// members that change.
+	UpdateRequest Schema
+
+	// Response is the schema of documents holding a single resource, along
+	// with the related resources it may include. Members tagged writeonly
+	// are left out, and attributes without the omitempty option are
+	// required.
+	Response Schema
+
+	// CollectionResponse is the schema of documents holding a list of
+	// resources.
+	CollectionResponse Schema
+}
+
+// JSONSchemas generates the JSON Schemas of the request and response
+// documents of model, a struct or a struct pointer, from its jsonapi tags.
+// Attributes are described by the JSON type of their field and their time
+// format; relationships by the resource type of their linkage. Custom
+// jsonapi-validate rules can't be described and are left out. The options
+// give the naming strategy of the member names. The model is checked with
+// CheckModel first and its error is returned.
+func JSONSchemas(model interface{}, opts ...Option) (*Schemas, error) {
+	if err := CheckModel(model, opts...); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	g := newSchemaGenerator(newOptions(opts).naming, "#/$defs/")
+	request := g.resourceRef(t, kindRequest)
+	update := g.resourceRef(t, kindUpdate)
+	response := g.resourceRef(t, kindResponse)
+	included := g.includedSchema()
+
+	schemas := &Schemas{
+		Request:            documentSchema(Schema{"$ref": request}, nil),
+		UpdateRequest:      documentSchema(Schema{"$ref": update}, nil),
+		Response:           documentSchema(Schema{"$ref": response}, included),
+		CollectionResponse: documentSchema(Schema{"type": "array", "items": Schema{"$ref": response}}, included),
+	}
+
+	// Every document carries its own copy of the definitions of all
+	// resource objects, as the request resources are generated alongside
+	// the response ones
+	for _, s := range []Schema{schemas.Request, schemas.UpdateRequest, schemas.Response, schemas.CollectionResponse} {
+		s["$schema"] = schemaDialect
+		s["$defs"] = copySchema(g.defs)
+	}
+
+	return schemas, nil
+}
+
+// documentSchema returns the schema of a top-level document with the primary
+// data data, and the included resources described by included unless it is
+// nil.
+func documentSchema(data, included Schema) Schema {
+	properties := Schema{
+		"data":    data,
+		"links":   linksSchema(),
+		"meta":    Schema{"type": "object"},
+		"jsonapi": Schema{"type": "object"},
+	}
+	if included != nil {
+		properties["included"] = Schema{"type": "array", "items": included}
+	}
+
+	return Schema{
+		"type":       "object",
+		"required":   []string{"data"},
+		"properties": properties,
+	}
+}
+
+// linksSchema returns the schema of a links object.
+func linksSchema() Schema {
+	return Schema{
+		"type": "object",
+		"additionalProperties": Schema{
+			"anyOf": []interface{}{
+				Schema{"type": "string"},
+				Schema{
+					"type":     "object",
+					"required": []string{"href"},
+					"properties": Schema{
+						"href": Schema{"type": "string"},
+						"meta": Schema{"type": "object"},
+					},
+				},
+				Schema{"type": "null"},
+			},
+		},
+	}
+}
+
+// schemaKind is the kind of document a resource object schema is generated
+// for.
+type schemaKind int
+
+const (
+	kindResponse schemaKind = iota
+	kindRequest
+	kindUpdate
+)
+
+// suffix returns the suffix of the definition names of the kind.
+func (k schemaKind) suffix() string {
+	switch k {
+	case kindRequest:
+		return "-request"
+	case kindUpdate:
+		return "-update-request"
+	default:
+		return ""
+	}
+}
+
+// schemaGenerator generates the schemas of resource objects as definitions
+// referred to by ref followed by their name.
+type schemaGenerator struct {
+	naming NamingStrategy
+	ref    string
+	defs   Schema
+
+	// related holds the names of the definitions of the resource objects
+	// that may be included in a response.
+	related map[string]bool
+
+	// nesting holds the nested struct types being generated, whose
+	// recursive fields are described as plain objects.
+	nesting map[reflect.Type]bool
+}
+
+func newSchemaGenerator(naming NamingStrategy, ref string) *schemaGenerator {
+	return &schemaGenerator{
+		naming:  naming,
+		ref:     ref,
+		defs:    Schema{},
+		related: map[string]bool{},
+		nesting: map[reflect.Type]bool{},
+	}
+}
+
+// includedSchema returns the schema of the resources that may be included
+// in a response, or nil if the generated resources have no relationships
+// with models.
+func (g *schemaGenerator) includedSchema() Schema {
+	if len(g.related) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(g.related))
+	for name := range g.related {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 1 {
+		return Schema{"$ref": g.ref + names[0]}
+	}
+
+	refs := make([]interface{}, len(names))
+	for i, name := range names {
+		refs[i] = Schema{"$ref": g.ref + name}
+	}
+
+	return Schema{"oneOf": refs}
+}
+
+// resourceName returns the definition name of the resource object of the
+// struct type t: its resource type, followed by "-request" for request
+// documents and "-update-request" for update request documents.
+func (g *schemaGenerator) resourceName(t reflect.Type, kind schemaKind) string {
+	info, _ := getModelInfo(t, g.naming)
+
+	return info.primaryName() + kind.suffix()
+}
+
+// resourceRef defines the resource object of the checked model type t,
+// along with the response resource objects of its related models, and
+// returns a reference to it.
+func (g *schemaGenerator) resourceRef(t reflect.Type, kind schemaKind) string {
+	name := g.resourceName(t, kind)
+	if _, ok := g.defs[name]; !ok {
+		// The placeholder ends the recursion of relationship cycles
+		g.defs[name] = nil
+		g.defs[name] = g.resourceSchema(t, kind)
+	}
+
+	return g.ref + name
+}
+
+// resourceSchema returns the schema of a resource object of the model type
+// t, as it is sent in the documents of kind.
+func (g *schemaGenerator) resourceSchema(t reflect.Type, kind schemaKind) Schema {
+	info, _ := getModelInfo(t, g.naming)
+	request := kind != kindResponse
+
+	attributes, required := Schema{}, []string{}
+	relationships, requiredRelationships := Schema{}, []string{}
+	properties := Schema{
+		"type": Schema{"const": info.primaryName()},
+		"id":   Schema{"type": "string"},
+	}
+
+	for _, field := range info.fields {
+		structField := t.Field(field.index)
+		if field.annotation == annotationClientID {
+			properties[annotationClientID] = Schema{"type": "string"}
+			continue
+		}
+		if field.annotation == annotationPrimary ||
+			(request && field.access == accessReadOnly) || (!request && field.access == accessWriteOnly) ||
+			(kind == kindUpdate && field.access == accessCreateOnly) {
+			continue
+		}
+
+		switch field.annotation {
+		case annotationAttribute:
+			s := g.typeSchema(structField.Type, parseTimeFormat(field.args))
+			if request {
+				s = ruleSchema(s, structField)
+			}
+			attributes[field.name] = s
+			if (!request && alwaysMarshaled(field, structField.Type)) ||
+				(kind == kindRequest && hasRequiredRule(structField)) {
+				required = append(required, field.name)
+			}
+		case annotationRelation:
+			rel := g.relationshipSchema(structField.Type, field, request)
+			if request {
+				data := rel["properties"].(Schema)
+				data["data"] = ruleSchema(data["data"].(Schema), structField)
+			}
+			relationships[field.name] = rel
+			if kind == kindRequest && hasRequiredRule(structField) {
+				requiredRelationships = append(requiredRelationships, field.name)
+			}
+		}
+	}
+
+	requiredMembers := []string{"type", "id"}
+	if kind == kindRequest {
+		requiredMembers = []string{"type"}
+	}
+
+	if len(attributes) > 0 {
+		attrs := Schema{"type": "object", "properties": attributes}
+		if len(required) > 0 {
+			attrs["required"] = required
+		}
+		properties["attributes"] = attrs
+	}
+	if len(relationships) > 0 {
+		rels := Schema{"type": "object", "properties": relationships}
+		if len(requiredRelationships) > 0 {
+			rels["required"] = requiredRelationships
+		}
+		properties["relationships"] = rels
+	}
+
+	// Unmarshaling create requests rejects required members that are left
+	// out, so the objects holding them are required too
+	if kind == kindRequest && len(required) > 0 {
+		requiredMembers = append(requiredMembers, "attributes")
+	}
+	if len(requiredRelationships) > 0 {
+		requiredMembers = append(requiredMembers, "relationships")
+	}
+	if !request {
+		properties["links"] = linksSchema()
+		properties["meta"] = Schema{"type": "object"}
+	}
+
+	return Schema{
+		"type":       "object",
+		"required":   requiredMembers,
+		"properties": properties,
+	}
+}
+
+// relationshipSchema returns the schema of the relationship object of the
+// relation field of type t. The related models of response relationships
+// are defined as resources that may be included.
+func (g *schemaGenerator) relationshipSchema(t reflect.Type, field *fieldInfo, request bool) Schema {
+	typ := field.linkageType
+	if typ == "" {
+		related := relatedType(t)
+		info, _ := getModelInfo(related, g.naming)
+		typ = info.primaryName()
+
+		if !request {
+			g.resourceRef(related, kindResponse)
+			g.related[g.resourceName(related, kindResponse)] = true
+		}
+	}
+
+	identifier := Schema{
+		"type":     "object",
+		"required": []string{"type", "id"},
+		"properties": Schema{
+			"type": Schema{"const": typ},
+			"id":   Schema{"type": "string"},
+			"meta": Schema{"type": "object"},
+		},
+	}
+
+	var data Schema
+	if t.Kind() == reflect.Slice {
+		data = Schema{"type": "array", "items": identifier}
+	} else {
+		data = Schema{"anyOf": []interface{}{identifier, Schema{"type": "null"}}}
+	}
+
+	if request {
+		return Schema{
+			"type":       "object",
+			"required":   []string{"data"},
+			"properties": Schema{"data": data},
+		}
+	}
+
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"data":  data,
+			"links": linksSchema(),
+			"meta":  Schema{"type": "object"},
+		},
+	}
+}
+
+// typeSchema returns the schema of the attribute values of type t, whose
+// times and durations are encoded in format.
+func (g *schemaGenerator) typeSchema(t reflect.Type, format timeFormat) Schema {
+	if isTimeType(t) {
+		switch format.layout {
+		case "":
+			return Schema{"type": "integer"}
+		case iso8601TimeFormat, time.RFC3339, time.RFC3339Nano:
+			return Schema{"type": "string", "format": "date-time"}
+		case dateTimeFormat:
+			return Schema{"type": "string", "format": "date"}
+		default:
+			return Schema{"type": "string"}
+		}
+	}
+	if t == durationType {
+		switch {
+		case format.layout == iso8601TimeFormat:
+			return Schema{"type": "string", "format": "duration"}
+		case format.durationUnit == time.Second:
+			return Schema{"type": "number"}
+		default:
+			return Schema{"type": "integer"}
+		}
+	}
+	if index, ok := nullValueIndex(t); ok {
+		return nullable(g.typeSchema(t.Field(index).Type, format))
+	}
+	if t.Kind() == reflect.Ptr {
+		return nullable(g.typeSchema(t.Elem(), format))
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice:
+		// Nil slices and maps are marshaled as null
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable(Schema{"type": "string", "contentEncoding": "base64"})
+		}
+		return nullable(Schema{"type": "array", "items": g.typeSchema(t.Elem(), format)})
+	case reflect.Array:
+		return Schema{"type": "array", "items": g.typeSchema(t.Elem(), format)}
+	case reflect.Map:
+		return nullable(Schema{"type": "object", "additionalProperties": g.typeSchema(t.Elem(), format)})
+	case reflect.Struct:
+		if hasJSONAPITags(t) && !g.nesting[t] {
+			return g.nestedSchema(t)
+		}
+		return Schema{"type": "object"}
+	default:
+		return Schema{}
+	}
+}
+
+// nestedSchema returns the schema of the attribute values of the struct type
+// t, whose fields are tagged as attributes.
+func (g *schemaGenerator) nestedSchema(t reflect.Type) Schema {
+	g.nesting[t] = true
+	defer delete(g.nesting, t)
+
+	info, _ := getModelInfo(t, g.naming)
+
+	properties, required := Schema{}, []string{}
+	for _, field := range info.fields {
+		if field.annotation != annotationAttribute {
+			continue
+		}
+		fieldType := t.Field(field.index).Type
+		properties[field.name] = g.typeSchema(fieldType, parseTimeFormat(field.args))
+		if alwaysMarshaled(field, fieldType) {
+			required = append(required, field.name)
+		}
+	}
+
+	s := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+// alwaysMarshaled reports whether the attribute field of type t is present in
+// every marshaled resource: it has no omitempty option and is not a time,
+// since zero times are left out.
+func alwaysMarshaled(field *fieldInfo, t reflect.Type) bool {
+	return !field.hasOption(annotationOmitEmpty) && !isTimeType(t)
+}
+
+// ruleSchema returns s, the schema of the values of structField, with the
+// constraints of its built-in jsonapi-validate rules. The rules have been
+// checked by CheckModel.
+func ruleSchema(s Schema, structField reflect.StructField) Schema {
+	tag, ok := structField.Tag.Lookup(annotationValidate)
+	if !ok {
+		return s
+	}
+
+	t := structField.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// The keywords bounding the length of values of type t
+	var minLength, maxLength string
+	switch t.Kind() {
+	case reflect.String:
+		minLength, maxLength = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			minLength, maxLength = "minItems", "maxItems"
+		}
+	case reflect.Map:
+		minLength, maxLength = "minProperties", "maxProperties"
+	}
+
+	s = copySchema(s)
+	for _, rule := range parseValidationRules(tag) {
+		switch rule.name {
+		case ruleRequired:
+			s = nonNull(s)
+		case ruleMin, ruleMax:
+			bound, _ := strconv.ParseFloat(rule.param, 64)
+			if rule.name == ruleMin {
+				s["minimum"] = bound
+			} else {
+				s["maximum"] = bound
+			}
+		case ruleLen, ruleMinLen, ruleMaxLen:
+			if minLength == "" {
+				continue
+			}
+			n, _ := strconv.Atoi(rule.param)
+			if rule.name != ruleMaxLen {
+				s[minLength] = n
+			}
+			if rule.name != ruleMinLen {
+				s[maxLength] = n
+			}
+		case ruleRegex:
+			s["pattern"] = rule.param
+		case ruleEnum:
+			if values, ok := enumValues(rule.param, t); ok {
+				if typ, ok := s["type"].([]string); ok && typ[len(typ)-1] == "null" {
+					values = append(values, nil)
+				}
+				s["enum"] = values
+			}
+		}
+	}
+
+	return s
+}
+
+// hasRequiredRule reports whether the jsonapi-validate tag of structField
+// has the required rule.
+func hasRequiredRule(structField reflect.StructField) bool {
+	for _, rule := range parseValidationRules(structField.Tag.Get(annotationValidate)) {
+		if rule.name == ruleRequired {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enumValues returns the values of an "enum" rule as JSON values of type t.
+func enumValues(param string, t reflect.Type) ([]interface{}, bool) {
+	var values []interface{}
+	for _, value := range strings.Split(param, ruleEnumSeparator) {
+		switch t.Kind() {
+		case reflect.String:
+			values = append(values, value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				continue
+			}
+			values = append(values, b)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			values = append(values, n)
+		default:
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+// nonNull returns a schema that accepts the values of s but null.
+func nonNull(s Schema) Schema {
+	if typ, ok := s["type"].([]string); ok && typ[len(typ)-1] == "null" {
+		s["type"] = typ[0]
+		return s
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && len(anyOf) == 2 {
+		if null, ok := anyOf[1].(Schema); ok && null["type"] == "null" {
+			return anyOf[0].(Schema)
+		}
+	}
+	if _, ok := s["type"]; ok {
+		return s
+	}
+
+	s["not"] = Schema{"type": "null"}
+	return s
+}
+
+// copySchema returns a deep copy of s.
+func copySchema(s Schema) Schema {
+	c := make(Schema, len(s))
+	for k, v := range s {
+		c[k] = copySchemaValue(v)
+	}
+
+	return c
+}
+
+func copySchemaValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case Schema:
+		if v == nil {
+			return v
+		}
+		return copySchema(v)
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i := range v {
+			c[i] = copySchemaValue(v[i])
+		}
+		return c
+	case []string:
+		return append([]string(nil), v...)
+	}
+
+	return v
+}
+
+// nullable returns a schema that also accepts null.
+func nullable(s Schema) Schema {
+	if _, ok := s["type"].([]string); ok {
+		// Already nullable, e.g. a pointer to a sql.Null type
+		return s
+	}
+	if typ, ok := s["type"].(string); ok {
+		n := Schema{}
+		for k, v := range s {
+			n[k] = v
+		}
+		n["type"] = []string{typ, "null"}
+		return n
+	}
+	if len(s) == 0 {
+		return s
+	}
+
+	return Schema{"anyOf": []interface{}{s, Schema{"type": "null"}}}
+}
@@ -0,0 +1,579 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// schemaAt returns the value of s at the path of member names.
+func schemaAt(t *testing.T, s Schema, path ...string) interface{} {
+	t.Helper()
+
+	var v interface{} = s
+	for _, name := range path {
+		m, ok := v.(Schema)
+		if !ok {
+			t.Fatalf("Was expecting a schema at %s in %v", name, path)
+		}
+		v = m[name]
+	}
+
+	return v
+}
+
+// assertJSON compares the JSON encoding of got with the JSON document want.
+func assertJSON(t *testing.T, got interface{}, want string) {
+	t.Helper()
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g, w interface{}
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("Was expecting %s, got %s", want, b)
+	}
+}
+
+// schemaValidator validates decoded JSON values against a decoded JSON
+// Schema, supporting the keywords JSONSchemas generates.
+type schemaValidator struct {
+	root interface{}
+}
+
+// validateDocument returns the violations of the JSON document doc against
+// schema.
+func validateDocument(t *testing.T, schema Schema, doc []byte) []string {
+	t.Helper()
+
+	var root, value interface{}
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &root); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(doc, &value); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &schemaValidator{root: root}
+	return v.validate(root, value, "")
+}
+
+func (v *schemaValidator) validate(schema, value interface{}, pointer string) []string {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf("%s: %s", pointer, fmt.Sprintf(format, args...)))
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target := v.root
+		for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			target = target.(map[string]interface{})[name]
+		}
+		errs = append(errs, v.validate(target, value, pointer)...)
+	}
+	if typ, ok := s["type"]; ok && !matchesType(typ, value) {
+		fail("%v is not of type %v", value, typ)
+		return errs
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("%v is not %v", value, c)
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, value)
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+	if not, ok := s["not"]; ok && len(v.validate(not, value, pointer)) == 0 {
+		fail("%v matches a forbidden schema", value)
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		schemas, ok := s[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		matches := 0
+		for _, sub := range schemas {
+			if len(v.validate(sub, value, pointer)) == 0 {
+				matches++
+			}
+		}
+		if matches == 0 || (keyword == "oneOf" && matches > 1) {
+			fail("%v matches %d of the %s schemas", value, matches, keyword)
+		}
+	}
+
+	bound := func(keyword string, n float64, below bool) {
+		if limit, ok := s[keyword].(float64); ok && (below && n < limit || !below && n > limit) {
+			fail("%v violates %s %v", value, keyword, limit)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := value[name.(string)]; !ok {
+					fail("missing required member %v", name)
+				}
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		for name, member := range value {
+			if property, ok := properties[name]; ok {
+				errs = append(errs, v.validate(property, member, pointer+"/"+name)...)
+			} else if additional, ok := s["additionalProperties"]; ok {
+				errs = append(errs, v.validate(additional, member, pointer+"/"+name)...)
+			}
+		}
+		bound("minProperties", float64(len(value)), true)
+		bound("maxProperties", float64(len(value)), false)
+	case []interface{}:
+		for i, item := range value {
+			errs = append(errs, v.validate(s["items"], item, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+		bound("minItems", float64(len(value)), true)
+		bound("maxItems", float64(len(value)), false)
+	case string:
+		bound("minLength", float64(utf8.RuneCountInString(value)), true)
+		bound("maxLength", float64(utf8.RuneCountInString(value)), false)
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			fail("%q does not match %s", value, pattern)
+		}
+	case float64:
+		bound("minimum", value, true)
+		bound("maximum", value, false)
+	}
+
+	return errs
+}
+
+func matchesType(typ, value interface{}) bool {
+	if types, ok := typ.([]interface{}); ok {
+		for _, t := range types {
+			if matchesType(t, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || typ == "integer" && value == math.Trunc(value)
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	}
+
+	return false
+}
+
+func TestJSONSchemas_marshaledDocuments(t *testing.T) {
+	type Probe struct {
+		ID      string         `jsonapi:"primary,probes"`
+		Tags    []string       `jsonapi:"attr,tags"`
+		Counts  map[string]int `jsonapi:"attr,counts"`
+		Created time.Time      `jsonapi:"attr,created"`
+		Name    string         `jsonapi:"attr,name"`
+	}
+
+	now := time.Date(2016, 8, 17, 8, 27, 12, 0, time.UTC)
+	period := 90 * time.Minute
+
+	for _, tc := range []struct {
+		desc   string
+		schema interface{}
+		models interface{}
+	}{
+		{"zero values", new(Probe), &Probe{ID: "1"}},
+		{"values", new(Probe), &Probe{ID: "1", Tags: []string{"a"}, Counts: map[string]int{"a": 1}, Created: now, Name: "b"}},
+		{"blog", new(Blog), testBlog()},
+		{"blogs", new(Blog), []*Blog{testBlog(), {ID: 6}}},
+		{"empty blogs", new(Blog), []*Blog{}},
+		{"company", new(Company), &Company{ID: "1", Teams: []Team{{Leader: &Employee{HiredAt: &now}}}}},
+		{"zero company", new(Company), &Company{ID: "1"}},
+		{"time formats", new(TimeFormats), &TimeFormats{ID: "1", Nano: now, Milli: now, Micro: &now, Date: now, Zoned: now, Custom: now, Dates: []time.Time{now}}},
+		{"zero time formats", new(TimeFormats), &TimeFormats{ID: "1"}},
+		{"schedule", new(Schedule), &Schedule{ID: "1", Timeout: period, TTL: period, Period: &period, Steps: []time.Duration{period}, Start: Date(now)}},
+		{"zero schedule", new(Schedule), &Schedule{ID: "1"}},
+		{"nullables", new(Nullables), &Nullables{ID: "1", Name: sql.NullString{String: "a", Valid: true}, Flags: []sql.NullBool{{}}}},
+		{"zero nullables", new(Nullables), &Nullables{ID: "1"}},
+		{"zero maps", new(MapAttributes), &MapAttributes{ID: "1"}},
+		{"zero slices", new(SliceAttributes), &SliceAttributes{ID: "1"}},
+		{"account", new(Account), &Account{ID: 1, Password: "secret", Manager: &Account{ID: 2}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			schemas, err := JSONSchemas(tc.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema := schemas.Response
+			if reflect.TypeOf(tc.models).Kind() == reflect.Slice {
+				schema = schemas.CollectionResponse
+			}
+
+			out := bytes.NewBuffer(nil)
+			if err := MarshalPayload(out, tc.models); err != nil {
+				t.Fatal(err)
+			}
+			if errs := validateDocument(t, schema, out.Bytes()); len(errs) > 0 {
+				t.Fatalf("%s does not match the schema:\n%s", out, strings.Join(errs, "\n"))
+			}
+		})
+	}
+}
+
+func TestJSONSchemas_validationRules(t *testing.T) {
+	schemas, err := JSONSchemas(new(ValidatedArticle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := schemaAt(t, schemas.Request, "$defs", "articles-request", "properties", "attributes", "properties").(Schema)
+	assertJSON(t, attributes["title"], `{"type": "string", "minLength": 3, "maxLength": 10}`)
+	assertJSON(t, attributes["rating"], `{"type": ["integer", "null"], "minimum": 1, "maximum": 5}`)
+	assertJSON(t, attributes["status"], `{"type": "string", "enum": ["draft", "published"]}`)
+	assertJSON(t, attributes["code"], `{"type": "string", "minLength": 2, "maxLength": 2}`)
+
+	// The rules constrain requests only
+	assertJSON(t, schemaAt(t, schemas.Response, "$defs", "articles", "properties", "attributes", "properties", "title"),
+		`{"type": "string"}`)
+
+	// Members with the required rule are required in create requests only
+	assertJSON(t, schemaAt(t, schemas.Request, "$defs", "articles-request", "required"),
+		`["type", "attributes", "relationships"]`)
+	assertJSON(t, schemaAt(t, schemas.Request, "$defs", "articles-request", "properties", "attributes", "required"),
+		`["title"]`)
+	assertJSON(t, schemaAt(t, schemas.Request, "$defs", "articles-request", "properties", "relationships", "required"),
+		`["author"]`)
+	update := schemaAt(t, schemas.UpdateRequest, "$defs", "articles-update-request").(Schema)
+	if schemaAt(t, update, "properties", "attributes", "required") != nil ||
+		schemaAt(t, update, "properties", "relationships", "required") != nil {
+		t.Fatal("Was not expecting required members in update requests")
+	}
+
+	// A document matches a schema if and only if it unmarshals
+	for _, tc := range []struct {
+		desc        string
+		attrs       map[string]interface{}
+		rels        map[string]interface{}
+		valid       bool
+		validUpdate bool
+	}{
+		{
+			desc:        "valid",
+			attrs:       map[string]interface{}{"title": "Hello", "rating": 3, "status": "draft", "slug": "hello-world", "code": "NL"},
+			rels:        validAuthor(),
+			valid:       true,
+			validUpdate: true,
+		},
+		{desc: "partial", attrs: map[string]interface{}{"rating": nil}, validUpdate: true},
+		{desc: "missing author", attrs: map[string]interface{}{"title": "Hello"}, validUpdate: true},
+		{desc: "null title", attrs: map[string]interface{}{"title": nil}},
+		{desc: "short title", attrs: map[string]interface{}{"title": "Hi"}},
+		{desc: "rating", attrs: map[string]interface{}{"rating": 6}},
+		{desc: "status", attrs: map[string]interface{}{"status": "deleted"}},
+		{desc: "slug", attrs: map[string]interface{}{"slug": "Hello World"}},
+		{desc: "null author", rels: map[string]interface{}{"author": map[string]interface{}{"data": nil}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.attrs == nil {
+				tc.attrs = map[string]interface{}{}
+			}
+			if tc.rels == nil {
+				tc.rels = map[string]interface{}{}
+			}
+			b, err := io.ReadAll(validatedArticlePayload(tc.attrs, tc.rels))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range []struct {
+				schema Schema
+				opts   []Option
+				valid  bool
+			}{
+				{schemas.Request, nil, tc.valid},
+				{schemas.UpdateRequest, []Option{ForUpdate()}, tc.validUpdate},
+			} {
+				errs := validateDocument(t, c.schema, b)
+				if c.valid && len(errs) > 0 {
+					t.Fatalf("Was expecting %s to be valid, got %v", b, errs)
+				}
+				if !c.valid && len(errs) == 0 {
+					t.Fatalf("Was expecting %s to be invalid", b)
+				}
+
+				err := UnmarshalPayload(bytes.NewReader(b), new(ValidatedArticle), c.opts...)
+				if c.valid != (err == nil) {
+					t.Fatalf("Was expecting %s to unmarshal like it validates, got %v", b, err)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchemas_documents(t *testing.T) {
+	schemas, err := JSONSchemas(new(Blog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Schema{schemas.Request, schemas.UpdateRequest, schemas.Response, schemas.CollectionResponse} {
+		if s["$schema"] != schemaDialect {
+			t.Fatalf("Was expecting the dialect, got %v", s["$schema"])
+		}
+
+		var defs []string
+		for name := range schemaAt(t, s, "$defs").(Schema) {
+			defs = append(defs, name)
+		}
+		sort.Strings(defs)
+		if !reflect.DeepEqual(defs, []string{"blogs", "blogs-request", "blogs-update-request", "comments", "posts"}) {
+			t.Fatalf("Unexpected definitions %v", defs)
+		}
+	}
+
+	assertJSON(t, schemaAt(t, schemas.Request, "properties", "data"), `{"$ref": "#/$defs/blogs-request"}`)
+	if schemaAt(t, schemas.Request, "properties", "included") != nil {
+		t.Fatal("Was not expecting included resources in requests")
+	}
+
+	assertJSON(t, schemaAt(t, schemas.Request, "$defs", "blogs-request", "required"), `["type"]`)
+	assertJSON(t, schemaAt(t, schemas.UpdateRequest, "properties", "data"), `{"$ref": "#/$defs/blogs-update-request"}`)
+	assertJSON(t, schemaAt(t, schemas.UpdateRequest, "$defs", "blogs-update-request", "required"), `["type", "id"]`)
+
+	assertJSON(t, schemaAt(t, schemas.Response, "properties", "data"), `{"$ref": "#/$defs/blogs"}`)
+	assertJSON(t, schemaAt(t, schemas.Response, "properties", "included"), `{"type": "array", "items": {
+		"oneOf": [{"$ref": "#/$defs/comments"}, {"$ref": "#/$defs/posts"}]}}`)
+	assertJSON(t, schemaAt(t, schemas.CollectionResponse, "properties", "data"),
+		`{"type": "array", "items": {"$ref": "#/$defs/blogs"}}`)
+
+	// The generated schemas are valid JSON documents
+	if _, err := json.Marshal(schemas); err != nil {
+		t.Fatal(err)
+	}
+
+	// and don't share their definitions
+	delete(schemaAt(t, schemas.Request, "$defs", "blogs").(Schema), "properties")
+	if schemaAt(t, schemas.Response, "$defs", "blogs", "properties") == nil {
+		t.Fatal("Was expecting the definitions of each schema to be distinct")
+	}
+}
+
+func TestJSONSchemas_resourceObjects(t *testing.T) {
+	schemas, err := JSONSchemas(new(Post))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := schemaAt(t, schemas.Response, "$defs").(Schema)
+
+	assertJSON(t, schemaAt(t, defs, "comments"), `{
+		"type": "object",
+		"required": ["type", "id"],
+		"properties": {
+			"type": {"const": "comments"},
+			"id": {"type": "string"},
+			"client-id": {"type": "string"},
+			"attributes": {
+				"type": "object",
+				"properties": {"post_id": {"type": "integer"}, "body": {"type": "string"}},
+				"required": ["post_id", "body"]
+			},
+			"links": {"type": "object", "additionalProperties": {"anyOf": [
+				{"type": "string"},
+				{"type": "object", "required": ["href"], "properties": {"href": {"type": "string"}, "meta": {"type": "object"}}},
+				{"type": "null"}
+			]}},
+			"meta": {"type": "object"}
+		}
+	}`)
+
+	identifier := `{"type": "object", "required": ["type", "id"], "properties": {
+		"type": {"const": "comments"}, "id": {"type": "string"}, "meta": {"type": "object"}}}`
+	assertJSON(t, schemaAt(t, defs, "posts-request", "required"), `["type"]`)
+	assertJSON(t, schemaAt(t, defs, "posts-request", "properties", "relationships"), `{
+		"type": "object",
+		"properties": {
+			"comments": {"type": "object", "required": ["data"], "properties": {
+				"data": {"type": "array", "items": `+identifier+`}}},
+			"latest_comment": {"type": "object", "required": ["data"], "properties": {
+				"data": {"anyOf": [`+identifier+`, {"type": "null"}]}}}
+		}
+	}`)
+}
+
+func TestJSONSchemas_attributeTypes(t *testing.T) {
+	for _, tc := range []struct {
+		model interface{}
+		want  string
+	}{
+		{new(TimeFormats), `{
+			"nano": {"type": "string", "format": "date-time"},
+			"milli": {"type": "integer"},
+			"micro": {"type": ["integer", "null"]},
+			"date": {"type": "string", "format": "date"},
+			"zoned": {"type": "string", "format": "date-time"},
+			"custom": {"type": "string"},
+			"dates": {"type": ["array", "null"], "items": {"type": "string", "format": "date"}}
+		}`},
+		{new(Schedule), `{
+			"timeout": {"type": "integer"},
+			"ttl": {"type": "number"},
+			"period": {"type": ["string", "null"], "format": "duration"},
+			"steps": {"type": ["array", "null"], "items": {"type": "string", "format": "duration"}},
+			"start": {"type": "string", "format": "date"},
+			"end": {"type": ["string", "null"], "format": "date"}
+		}`},
+		{new(Nullables), `{
+			"name": {"type": ["string", "null"]},
+			"count": {"type": ["integer", "null"]},
+			"score": {"type": ["number", "null"]},
+			"deleted": {"type": ["string", "null"], "format": "date-time"},
+			"flags": {"type": ["array", "null"], "items": {"type": ["boolean", "null"]}},
+			"code": {"type": ["string", "null"]}
+		}`},
+	} {
+		schemas, err := JSONSchemas(tc.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt := reflect.TypeOf(tc.model).Elem()
+		info, _ := getModelInfo(rt, newOptions(nil).naming)
+
+		assertJSON(t, schemaAt(t, schemas.Response, "$defs", info.primaryName(), "properties", "attributes", "properties"), tc.want)
+	}
+}
+
+func TestJSONSchemas_access(t *testing.T) {
+	type Account struct {
+		ID       string            `jsonapi:"primary,accounts"`
+		Email    string            `jsonapi:"attr,email" jsonapi-validate:"required"`
+		Nickname string            `jsonapi:"attr,nickname,omitempty"`
+		Balance  uint              `jsonapi:"attr,balance,readonly"`
+		Password string            `jsonapi:"attr,password,writeonly"`
+		Settings map[string]string `jsonapi:"attr,settings,createonly,omitempty"`
+		Address  *Address          `jsonapi:"attr,address,omitempty"`
+		Manager  Employee          `jsonapi:"attr,manager,omitempty"`
+	}
+
+	schemas, err := JSONSchemas(new(Account))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := schemaAt(t, schemas.Response, "$defs").(Schema)
+
+	assertJSON(t, schemaAt(t, defs, "accounts", "properties", "attributes"), `{
+		"type": "object",
+		"properties": {
+			"email": {"type": "string"},
+			"nickname": {"type": "string"},
+			"balance": {"type": "integer", "minimum": 0},
+			"settings": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+			"address": {"type": ["object", "null"]},
+			"manager": {"type": "object", "properties": {
+				"firstname": {"type": "string"},
+				"surname": {"type": "string"},
+				"age": {"type": "integer"},
+				"hired-at": {"type": ["string", "null"], "format": "date-time"}
+			}, "required": ["firstname", "surname", "age", "hired-at"]}
+		},
+		"required": ["email", "balance"]
+	}`)
+
+	var members []string
+	for name := range schemaAt(t, defs, "accounts-request", "properties", "attributes", "properties").(Schema) {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"address", "email", "manager", "nickname", "password", "settings"}) {
+		t.Fatalf("Unexpected request attributes %v", members)
+	}
+	assertJSON(t, schemaAt(t, defs, "accounts-request", "properties", "attributes", "required"), `["email"]`)
+	assertJSON(t, schemaAt(t, defs, "accounts-request", "required"), `["type", "attributes"]`)
+	if schemaAt(t, defs, "accounts-update-request", "properties", "attributes", "required") != nil {
+		t.Fatal("Was not expecting required attributes in update requests")
+	}
+
+	// Update requests leave out createonly members as well
+	members = nil
+	for name := range schemaAt(t, defs, "accounts-update-request", "properties", "attributes", "properties").(Schema) {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"address", "email", "manager", "nickname", "password"}) {
+		t.Fatalf("Unexpected update request attributes %v", members)
+	}
+}
+
+func TestJSONSchemas_identifierRelations(t *testing.T) {
+	schemas, err := JSONSchemas(new(Note))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if schemaAt(t, schemas.Response, "properties", "included") != nil {
+		t.Fatal("Was not expecting included resources for identifier-only relations")
+	}
+	assertJSON(t, schemaAt(t, schemas.Response, "$defs", "notes", "properties", "relationships",
+		"properties", "tags", "properties", "data", "items", "properties", "type"), `{"const": "tags"}`)
+}
+
+func TestJSONSchemas_invalidModel(t *testing.T) {
+	if _, err := JSONSchemas(new(BadModel)); !errors.Is(err, ErrBadJSONAPIStructTag) {
+		t.Fatalf("Was expecting the error of CheckModel, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+var (
+	// ErrUnknownMember is returned in strict mode, wrapped in a *FieldError,
+	// for an attribute or relationship that does not map to a struct field
+	// and for a top-level member that a request document may not have.
+	ErrUnknownMember = errors.New("unknown member")
+
+	// ErrClientIDNotAllowed is returned, wrapped in a *FieldError, when the
+	// primary data of a request document has an "id" and the
+	// DisallowClientIDs option is given. Its error object has the
+	// "403 Forbidden" status required by the specification.
+	ErrClientIDNotAllowed error = forbiddenError("client-generated ids are not allowed")
+)
+
+// requestMembers are the top-level members allowed in a request document.
+var requestMembers = memberSet("data", "meta", "jsonapi", "links", "included")
+
+// forbiddenError is an error reported with the "403 Forbidden" status.
+type forbiddenError string
+
+// Error implements the `Error` interface.
+func (e forbiddenError) Error() string {
+	return string(e)
+}
+
+// ErrorObject converts the error into a "403 Forbidden" error object.
+func (e forbiddenError) ErrorObject() *ErrorObject {
+	return &ErrorObject{
+		Title:  "Forbidden",
+		Detail: e.Error(),
+		Status: "403",
+	}
+}
+
+// Strict makes the unmarshal functions reject members they would otherwise
+// ignore: attributes and relationships that do not map to a struct field,
+// including the members of nested struct attributes, and top-level members
+// other than "data", "included", "meta", "jsonapi" and "links". Each of them
+// is reported as a *FieldError wrapping ErrUnknownMember. @-members are always
+// ignored, as the specification requires.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// DisallowClientIDs makes the unmarshal functions reject primary data that
+// has an "id", with a *FieldError wrapping ErrClientIDNotAllowed. Pass it when
+// unmarshaling the body of a create request for resources whose ids are
+// generated by the server.
+func DisallowClientIDs() Option {
+	return func(o *options) {
+		o.disallowClientIDs = true
+	}
+}
+
+// decodeRequest reads the request document from in into payload. In strict
+// mode the top-level members of the document are checked first.
+func (o *options) decodeRequest(in io.Reader, payload interface{}) error {
+	if !o.strict {
+		return o.decode(in, payload)
+	}
+
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	if err := o.unmarshal(b, &members); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := o.checkTopLevelMember(name); err != nil {
+			if !o.collectErrors {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return MultiError(errs)
+	}
+
+	return o.unmarshal(b, payload)
+}
+
+// checkTopLevelMember returns an error if strict mode is on and name may not
+// be a top-level member of a request document.
+func (o *options) checkTopLevelMember(name string) error {
+	if !o.strict || requestMembers[name] || isAtMember(name) {
+		return nil
+	}
+
+	pointer := pointerTo("", name)
+	return newFieldError(pointer, "", fmt.Errorf("%w %q", ErrUnknownMember, name))
+}
+
+// checkMembers reports, through fail, the attributes and relationships of
+// data that do not map to a field of the model described by info. It returns
+// true if unmarshaling should stop.
+func checkMembers(data *Node, info *modelInfo, pointer string, fail func(at, field string, err error) bool) bool {
+	for _, object := range []struct {
+		name       string
+		annotation string
+		members    map[string]interface{}
+	}{
+		{"attributes", annotationAttribute, data.Attributes},
+		{"relationships", annotationRelation, data.Relationships},
+	} {
+		for _, name := range sortedKeys(object.members) {
+			if isAtMember(name) || info.hasMember(object.annotation, name) {
+				continue
+			}
+
+			at := memberPointer(pointer, object.name, name)
+			err := fmt.Errorf("%w %q", ErrUnknownMember, name)
+			if fail(at, "", newFieldError(at, "", err)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unmarshalPrimary populates model from data, a resource object of the
+// primary data, rejecting its "id" if client-generated ids are not allowed.
+func (ctx *unmarshalContext) unmarshalPrimary(data *Node, model reflect.Value, pointer string) error {
+	if err := checkModel(model); err != nil {
+		return err
+	}
+
+	if !ctx.opts.disallowClientIDs || data == nil || data.ID == "" {
+		return ctx.unmarshalNode(data, model, pointer)
+	}
+
+	idErr := newFieldError(pointer+"/id", "", ErrClientIDNotAllowed)
+	if !ctx.opts.collectErrors {
+		return idErr
+	}
+
+	errs := []error{idErr}
+	if err := ctx.unmarshalNode(data, model, pointer); err != nil {
+		errs = appendErrors(errs, err)
+	}
+
+	return MultiError(errs)
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrict_unknownMembers(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc     string
+		pointer string
+	}{
+		"attribute": {
+			doc:     `{"data": {"type": "blogs", "attributes": {"title": "Title", "titel": "Title"}}}`,
+			pointer: "/data/attributes/titel",
+		},
+		"relationship": {
+			doc:     `{"data": {"type": "blogs", "relationships": {"author": {"data": null}}}}`,
+			pointer: "/data/relationships/author",
+		},
+		"top-level member": {
+			doc:     `{"data": {"type": "blogs"}, "extra": true}`,
+			pointer: "/extra",
+		},
+		"errors member": {
+			doc:     `{"data": {"type": "blogs"}, "errors": []}`,
+			pointer: "/errors",
+		},
+		"nested attribute": {
+			doc:     `{"data": {"type": "companies", "attributes": {"boss": {"firstname": "Jane", "nickname": "J"}}}}`,
+			pointer: "/data/attributes/boss/nickname",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			model := interface{}(new(Blog))
+			if strings.Contains(tc.doc, "companies") {
+				model = new(Company)
+			}
+
+			if err := UnmarshalPayload(strings.NewReader(tc.doc), model); err != nil {
+				t.Fatalf("Was expecting the member to be ignored by default, got %v", err)
+			}
+
+			err := UnmarshalPayload(strings.NewReader(tc.doc), model, Strict())
+			if !errors.Is(err, ErrUnknownMember) {
+				t.Fatalf("Was expecting ErrUnknownMember, got %v", err)
+			}
+			var ferr *FieldError
+			if !errors.As(err, &ferr) || ferr.Pointer != tc.pointer {
+				t.Fatalf("Was expecting a *FieldError at %s, got %v", tc.pointer, err)
+			}
+			if obj := ferr.ErrorObject(); obj.Status != "400" || obj.Source.Pointer != tc.pointer {
+				t.Fatalf("Unexpected error object %+v", obj)
+			}
+		})
+	}
+}
+
+func TestStrict_allowedMembers(t *testing.T) {
+	doc := `{
+		"data": {
+			"type": "blogs",
+			"attributes": {"title": "Title", "@note": "ignored"},
+			"relationships": {"posts": {"data": []}}
+		},
+		"included": [],
+		"meta": {"request-id": "1"},
+		"jsonapi": {"version": "1.1"},
+		"links": {"self": "https://example.com/blogs"},
+		"@note": "ignored"
+	}`
+
+	blog := new(Blog)
+	if err := UnmarshalPayload(strings.NewReader(doc), blog, Strict()); err != nil {
+		t.Fatal(err)
+	}
+	if blog.Title != "Title" {
+		t.Fatalf("Unexpected blog %+v", blog)
+	}
+}
+
+func TestStrict_collectErrors(t *testing.T) {
+	doc := `{"data": [{"type": "blogs", "attributes": {"a": 1, "b": 2}, "relationships": {"c": {"data": null}}}]}`
+
+	_, err := UnmarshalManyPayload(strings.NewReader(doc), reflect.TypeOf(new(Blog)), Strict(), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var pointers []string
+	for _, obj := range merr.ErrorObjects() {
+		pointers = append(pointers, obj.Source.Pointer)
+	}
+	expected := []string{
+		"/data/0/attributes/a",
+		"/data/0/attributes/b",
+		"/data/0/relationships/c",
+	}
+	if !reflect.DeepEqual(pointers, expected) {
+		t.Fatalf("Was expecting errors at %v, got %v", expected, pointers)
+	}
+}
+
+func TestDisallowClientIDs(t *testing.T) {
+	doc := `{"data": {"type": "blogs", "id": "5", "attributes": {"title": "Title"}}}`
+
+	if err := UnmarshalPayload(strings.NewReader(doc), new(Blog)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UnmarshalPayload(strings.NewReader(doc), new(Blog), DisallowClientIDs())
+	if !errors.Is(err, ErrClientIDNotAllowed) {
+		t.Fatalf("Was expecting ErrClientIDNotAllowed, got %v", err)
+	}
+
+	var ferr *FieldError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Was expecting a *FieldError, got %T", err)
+	}
+	if obj := ferr.ErrorObject(); obj.Status != "403" || obj.Source.Pointer != "/data/id" {
+		t.Fatalf("Unexpected error object %+v", obj)
+	}
+
+	noID := `{"data": {"type": "blogs", "attributes": {"title": "Title"}}}`
+	if err := UnmarshalPayload(strings.NewReader(noID), new(Blog), DisallowClientIDs()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDisallowClientIDs_onlyPrimaryData(t *testing.T) {
+	doc := `{
+		"data": {
+			"type": "blogs",
+			"relationships": {"current_post": {"data": {"type": "posts", "id": "2"}}}
+		},
+		"included": [{"type": "posts", "id": "2", "attributes": {"title": "Post"}}]
+	}`
+
+	blog := new(Blog)
+	if err := UnmarshalPayload(strings.NewReader(doc), blog, Strict(), DisallowClientIDs()); err != nil {
+		t.Fatal(err)
+	}
+	if blog.CurrentPost == nil || blog.CurrentPost.ID != 2 {
+		t.Fatalf("Unexpected relation %+v", blog.CurrentPost)
+	}
+}
+
+func TestDecoder_strict(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc  string
+		want error
+	}{
+		"before data": {
+			doc:  `{"extra": 1, "data": [{"type": "blogs"}]}`,
+			want: ErrUnknownMember,
+		},
+		"after data": {
+			doc:  `{"data": {"type": "blogs"}, "extra": 1}`,
+			want: ErrUnknownMember,
+		},
+		"unknown attribute": {
+			doc:  `{"data": [{"type": "blogs", "attributes": {"extra": 1}}]}`,
+			want: ErrUnknownMember,
+		},
+		"client id": {
+			doc:  `{"data": [{"type": "blogs", "id": "1"}]}`,
+			want: ErrClientIDNotAllowed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tc.doc), Strict(), DisallowClientIDs())
+
+			var err error
+			for err == nil {
+				err = dec.Decode(new(Blog))
+			}
+			if err == io.EOF || !errors.Is(err, tc.want) {
+				t.Fatalf("Was expecting %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
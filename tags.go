@@ -0,0 +1,327 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidMemberName is returned when the type of a primary field or the
+// member name of an attribute or relation does not conform to the member name
+// rules of the JSON API specification, or is the reserved name "type" or
+// "id". It is wrapped in a *TagError.
+var ErrInvalidMemberName = errors.New("invalid member name")
+
+// ErrUnexportedField is returned when a struct field with a jsonapi tag is
+// not exported, so that it can be neither read nor set. It is wrapped in a
+// *TagError.
+var ErrUnexportedField = errors.New("field is not exported")
+
+// ErrUnsupportedRelation is returned when a relation field is not a struct, a
+// struct pointer, or a slice of either, or, with the type option, an id or a
+// slice of ids. It is wrapped in a *TagError.
+var ErrUnsupportedRelation = errors.New("relation must be a struct, a struct pointer or a slice of either, or ids with the type option")
+
+// TagError is returned when the jsonapi struct tag of a field can't be used.
+type TagError struct {
+	// Type is the struct type declaring the field.
+	Type reflect.Type
+
+	// Field is the name of the struct field.
+	Field string
+
+	// Tag is the value of the jsonapi struct tag, or of the jsonapi-validate
+	// tag for an invalid rule.
+	Tag string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the `Error` interface.
+func (e *TagError) Error() string {
+	return fmt.Sprintf("jsonapi: struct field `%s` of %v has an invalid tag %q: %v",
+		e.Field, e.Type, e.Tag, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TagError) Unwrap() error {
+	return e.Err
+}
+
+// fieldInfo describes a struct field annotated with a jsonapi tag.
+type fieldInfo struct {
+	index int
+
+	// annotation is the first argument of the tag, e.g. "attr".
+	annotation string
+
+	// name is the resource type of a primary field and the member name of an
+	// attribute or relation.
+	name string
+
+	// args is the tag split into its arguments, with a derived member name
+	// filled in.
+	args []string
+
+	// direct reports whether the value of an attribute can be written by the
+	// encodeState without going through encoding/json.
+	direct bool
+
+	// composite reports whether the value of an attribute is converted by
+	// attributeValue before it is encoded.
+	composite bool
+
+	// access is set by the readonly, writeonly and createonly options of an
+	// attribute or relation.
+	access fieldAccess
+
+	// linkageType is the resource type given by the type option of an
+	// identifier-only relation, whose field holds the ids of the related
+	// resources instead of the resources.
+	linkageType string
+}
+
+// hasOption reports whether the tag options following the member name
+// include opt.
+func (field *fieldInfo) hasOption(opt string) bool {
+	if len(field.args) < 3 {
+		return false
+	}
+	for _, arg := range field.args[2:] {
+		if arg == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// modelInfo holds the parsed jsonapi tags of a struct type.
+type modelInfo struct {
+	fields []*fieldInfo
+	err    error
+}
+
+// hasMember reports whether the model has a field with the given annotation
+// and member name.
+func (info *modelInfo) hasMember(annotation, name string) bool {
+	for _, field := range info.fields {
+		if field.annotation == annotation && field.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// primaryName returns the resource type given by the primary field of the
+// model, or "" if it has none.
+func (info *modelInfo) primaryName() string {
+	for _, field := range info.fields {
+		if field.annotation == annotationPrimary {
+			return field.name
+		}
+	}
+
+	return ""
+}
+
+type modelInfoKey struct {
+	t      reflect.Type
+	naming NamingStrategy
+}
+
+// modelInfoCache maps modelInfoKey values to *modelInfo.
+var modelInfoCache sync.Map
+
+// getModelInfo returns the parsed jsonapi tags of the struct type t. Tags are
+// parsed once per type and naming strategy; a malformed tag is reported on
+// every use of the type.
+func getModelInfo(t reflect.Type, naming NamingStrategy) (*modelInfo, error) {
+	key := modelInfoKey{t, naming}
+	if cached, ok := modelInfoCache.Load(key); ok {
+		info := cached.(*modelInfo)
+		return info, info.err
+	}
+
+	info := parseModelInfo(t, naming)
+	modelInfoCache.Store(key, info)
+
+	return info, info.err
+}
+
+func parseModelInfo(t reflect.Type, naming NamingStrategy) *modelInfo {
+	info := new(modelInfo)
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		tag := structField.Tag.Get(annotationJSONAPI)
+		if tag == "" {
+			continue
+		}
+
+		field, err := parseFieldTag(structField, tag, naming)
+		if err != nil {
+			if err != ErrBadJSONAPIStructTag {
+				err = &TagError{Type: t, Field: structField.Name, Tag: tag, Err: err}
+			}
+			info.err = err
+			return info
+		}
+		field.index = i
+
+		info.fields = append(info.fields, field)
+	}
+
+	return info
+}
+
+func parseFieldTag(structField reflect.StructField, tag string, naming NamingStrategy) (*fieldInfo, error) {
+	if !structField.IsExported() {
+		return nil, ErrUnexportedField
+	}
+
+	args := strings.Split(tag, annotationSeperator)
+	annotation := args[0]
+
+	switch annotation {
+	case annotationClientID:
+		if len(args) != 1 {
+			return nil, ErrBadJSONAPIStructTag
+		}
+		if structField.Type.Kind() != reflect.String {
+			return nil, fmt.Errorf("client-id field must be a string, not %v", structField.Type)
+		}
+	case annotationPrimary:
+		if len(args) < 2 {
+			return nil, ErrBadJSONAPIStructTag
+		}
+		if !isValidMemberName(args[1]) {
+			return nil, ErrInvalidMemberName
+		}
+	case annotationAttribute, annotationRelation:
+		if len(args) < 2 {
+			args = append(args, "")
+		}
+		if args[1] == "" {
+			args[1] = naming.memberName(structField.Name)
+			if args[1] == "" {
+				return nil, ErrBadJSONAPIStructTag
+			}
+		}
+		if args[1] == "type" || args[1] == "id" || !isValidMemberName(args[1]) {
+			return nil, ErrInvalidMemberName
+		}
+	default:
+		return nil, fmt.Errorf(unsupportedStructTagMsg, annotation)
+	}
+
+	field := &fieldInfo{
+		annotation: annotation,
+		args:       args,
+		direct:     annotation == annotationAttribute && isDirectType(structField.Type),
+		composite:  annotation == annotationAttribute && isComposite(structField.Type),
+	}
+	if len(args) > 1 {
+		field.name = args[1]
+	}
+	if len(args) > 2 {
+		access, err := parseAccess(args[2:])
+		if err != nil {
+			return nil, err
+		}
+		field.access = access
+	}
+
+	if annotation == annotationRelation {
+		if err := field.parseLinkageType(structField.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	return field, nil
+}
+
+// parseLinkageType sets the linkageType of a relation field of type t from
+// its type option, and checks that t can hold the related resources, or
+// their ids with the option.
+func (field *fieldInfo) parseLinkageType(t reflect.Type) error {
+	for _, arg := range field.args[2:] {
+		if typ, ok := strings.CutPrefix(arg, annotationType+"="); ok {
+			if !isValidMemberName(typ) {
+				return ErrInvalidMemberName
+			}
+			field.linkageType = typ
+		}
+	}
+
+	if field.linkageType == "" {
+		if relatedType(t) == nil {
+			return ErrUnsupportedRelation
+		}
+		return nil
+	}
+
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if !isIDType(t) {
+		return ErrUnsupportedRelation
+	}
+
+	return nil
+}
+
+// relatedType returns the struct type of the related resources of a relation
+// field of type t, or nil if t is not a struct, a struct pointer, or a slice
+// of either.
+func relatedType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
+
+// relatedModel returns the struct pointer of the related resource v, an
+// element of a relation field. Struct values are addressed within the model.
+func relatedModel(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Struct {
+		return v.Addr()
+	}
+
+	return v
+}
+
+// isNullRelation reports whether v, the value of a to-one relation field, is
+// a nil pointer or the zero value of a struct or an id.
+func isNullRelation(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		return v.IsNil()
+	}
+
+	return v.IsZero()
+}
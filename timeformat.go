@@ -0,0 +1,233 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	timeFormatsMu sync.RWMutex
+	timeFormats   = map[string]string{}
+)
+
+// RegisterTimeFormat makes layout, in the form accepted by time.Format,
+// available as the time option name in jsonapi struct tags, e.g.
+// RegisterTimeFormat("rfc1123", time.RFC1123Z) enables
+// `jsonapi:"attr,expires,rfc1123"`. It panics if name or layout is empty or
+// if name is a built-in tag option.
+func RegisterTimeFormat(name, layout string) {
+	switch name {
+	case "", annotationOmitEmpty, annotationISO8601, annotationRFC3339,
+		annotationRFC3339Nano, annotationUnixMilli, annotationUnixMicro,
+		annotationDate, annotationSeconds, annotationKeepZone, annotationReadOnly,
+		annotationWriteOnly, annotationCreateOnly:
+		panic(fmt.Sprintf("jsonapi: invalid time format name %q", name))
+	}
+	if layout == "" {
+		panic(fmt.Sprintf("jsonapi: empty layout for time format %q", name))
+	}
+
+	timeFormatsMu.Lock()
+	defer timeFormatsMu.Unlock()
+
+	timeFormats[name] = layout
+}
+
+func lookupTimeFormat(name string) (string, bool) {
+	timeFormatsMu.RLock()
+	defer timeFormatsMu.RUnlock()
+
+	layout, ok := timeFormats[name]
+	return layout, ok
+}
+
+// isTimeFormatOption reports whether opt is a built-in or registered time
+// format option.
+func isTimeFormatOption(opt string) bool {
+	switch opt {
+	case annotationISO8601, annotationRFC3339, annotationRFC3339Nano, annotationDate,
+		annotationSeconds, annotationUnixMilli, annotationUnixMicro:
+		return true
+	}
+
+	_, ok := lookupTimeFormat(opt)
+	return ok
+}
+
+// timeFormat is the encoding of the time.Time values of an attribute: a
+// string in layout, or a unix timestamp counting units since the epoch.
+type timeFormat struct {
+	layout string
+	unit   time.Duration
+
+	// durationUnit is the unit of the numbers that time.Duration values are
+	// encoded as, unless they are ISO 8601 duration strings.
+	durationUnit time.Duration
+
+	// err is returned for a value that is not in the format.
+	err error
+
+	// keepZone keeps the offset of times in string formats instead of
+	// converting them to UTC.
+	keepZone bool
+}
+
+// parseTimeFormat returns the time format among the tag options of an
+// attribute. The first format option wins; without one, times are unix
+// timestamps in seconds.
+func parseTimeFormat(args []string) timeFormat {
+	f := timeFormat{unit: time.Second, durationUnit: time.Nanosecond, err: ErrInvalidTime}
+	if len(args) <= 2 {
+		return f
+	}
+
+	var found bool
+	for _, arg := range args[2:] {
+		if arg == annotationKeepZone {
+			f.keepZone = true
+			continue
+		}
+		if found {
+			continue
+		}
+
+		found = true
+		switch arg {
+		case annotationISO8601:
+			f.layout, f.err = iso8601TimeFormat, ErrInvalidISO8601
+		case annotationRFC3339:
+			f.layout, f.err = time.RFC3339, ErrInvalidRFC3339
+		case annotationRFC3339Nano:
+			f.layout, f.err = time.RFC3339Nano, ErrInvalidRFC3339
+		case annotationDate:
+			f.layout, f.err = dateTimeFormat, ErrInvalidTimeFormat
+		case annotationSeconds:
+			f.durationUnit = time.Second
+		case annotationUnixMilli:
+			f.unit = time.Millisecond
+		case annotationUnixMicro:
+			f.unit = time.Microsecond
+		default:
+			var layout string
+			if layout, found = lookupTimeFormat(arg); found {
+				f.layout, f.err = layout, ErrInvalidTimeFormat
+			}
+		}
+	}
+
+	return f
+}
+
+// isTimeType reports whether t is time.Time or a named type whose underlying
+// type is time.Time, such as a date type declared as `type Date time.Time`,
+// that doesn't encode itself.
+func isTimeType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+
+	return t.Kind() == reflect.Struct && t.ConvertibleTo(timeType) && !isMarshaler(t)
+}
+
+// timeValue returns the time of v, a value of a type for which isTimeType
+// reports true.
+func timeValue(v reflect.Value) time.Time {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time)
+	}
+
+	return v.Convert(timeType).Interface().(time.Time)
+}
+
+// zoned returns t in the location it is formatted in. The ISO8601 layout has
+// a literal "Z", so its times are always in UTC.
+func (f timeFormat) zoned(t time.Time) time.Time {
+	if f.keepZone && f.layout != iso8601TimeFormat {
+		return t
+	}
+
+	return t.UTC()
+}
+
+// unix returns t as a number of units since the epoch.
+func (f timeFormat) unix(t time.Time) int64 {
+	switch f.unit {
+	case time.Millisecond:
+		return t.UnixMilli()
+	case time.Microsecond:
+		return t.UnixMicro()
+	}
+
+	return t.Unix()
+}
+
+// format returns the attribute value of t.
+func (f timeFormat) format(t time.Time) interface{} {
+	if f.layout == "" {
+		return f.unix(t)
+	}
+
+	return f.zoned(t).Format(f.layout)
+}
+
+// append appends the JSON encoding of t, as format returns it, to dst.
+func (f timeFormat) append(dst []byte, t time.Time) []byte {
+	if f.layout == "" {
+		return strconv.AppendInt(dst, f.unix(t), 10)
+	}
+
+	return appendString(dst, f.zoned(t).Format(f.layout))
+}
+
+// parse returns the time of the attribute value v, a string in the layout or
+// a unix timestamp whose fraction of a unit is kept.
+func (f timeFormat) parse(v reflect.Value) (time.Time, error) {
+	if f.layout != "" {
+		if v.Kind() != reflect.String {
+			return time.Time{}, f.err
+		}
+
+		t, err := time.Parse(f.layout, v.String())
+		if err != nil {
+			return time.Time{}, f.err
+		}
+
+		return t, nil
+	}
+
+	var n int64
+	var frac float64
+
+	switch v.Kind() {
+	case reflect.Float64:
+		whole, fraction := math.Modf(v.Float())
+		n, frac = int64(whole), fraction
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	default:
+		return time.Time{}, f.err
+	}
+
+	perSecond := int64(time.Second / f.unit)
+	nsec := n%perSecond*int64(f.unit) + int64(math.Round(frac*float64(f.unit)))
+
+	return time.Unix(n/perSecond, nsec), nil
+}
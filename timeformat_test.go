@@ -0,0 +1,158 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterTimeFormat("rfc1123", time.RFC1123Z)
+}
+
+func TestTimeFormats_marshal(t *testing.T) {
+	zone := time.FixedZone("", 2*60*60)
+	at := time.Date(2016, 8, 17, 23, 27, 12, 123456789, time.UTC)
+
+	model := &TimeFormats{
+		ID:     "1",
+		Nano:   at,
+		Milli:  at,
+		Micro:  &at,
+		Date:   at.In(zone),
+		Zoned:  at.In(zone),
+		Custom: at,
+		Dates:  []time.Time{at, at.AddDate(0, 0, 1)},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, model); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Data struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"nano":   "2016-08-17T23:27:12.123456789Z",
+		"milli":  float64(1471476432123),
+		"micro":  float64(1471476432123456),
+		"date":   "2016-08-17",
+		"zoned":  "2016-08-18T01:27:12+02:00",
+		"custom": "Wed, 17 Aug 2016 23:27:12 +0000",
+		"dates":  []interface{}{"2016-08-17", "2016-08-18"},
+	}
+	if !reflect.DeepEqual(doc.Data.Attributes, expected) {
+		t.Fatalf("Was expecting %v, got %v", expected, doc.Data.Attributes)
+	}
+}
+
+func TestTimeFormats_unmarshal(t *testing.T) {
+	doc := `{"data": {"type": "time-formats", "id": "1", "attributes": {
+		"nano": "2016-08-17T23:27:12.123456789Z",
+		"milli": 1471476432123,
+		"micro": 1471476432123456,
+		"date": "2016-08-17",
+		"zoned": "2016-08-18T01:27:12+02:00",
+		"custom": "Wed, 17 Aug 2016 23:27:12 +0000",
+		"dates": ["2016-08-17", "2016-08-18"]
+	}}}`
+
+	model := new(TimeFormats)
+	if err := UnmarshalPayload(strings.NewReader(doc), model); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2016, 8, 17, 23, 27, 12, 123456789, time.UTC)
+	day := time.Date(2016, 8, 17, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		got, expected time.Time
+	}{
+		"nano":   {model.Nano, at},
+		"milli":  {model.Milli, at.Truncate(time.Millisecond)},
+		"micro":  {*model.Micro, at.Truncate(time.Microsecond)},
+		"date":   {model.Date, day},
+		"custom": {model.Custom, at.Truncate(time.Second)},
+		"dates":  {model.Dates[1], day.AddDate(0, 0, 1)},
+	} {
+		if !tc.got.Equal(tc.expected) {
+			t.Errorf("%s: was expecting %v, got %v", name, tc.expected, tc.got)
+		}
+	}
+
+	if _, offset := model.Zoned.Zone(); offset != 2*60*60 {
+		t.Fatalf("Was expecting the offset to be kept, got %v", model.Zoned)
+	}
+}
+
+func TestTimeFormats_unixFraction(t *testing.T) {
+	doc := `{"data": {"type": "timestamps", "attributes": {"defaultv": 1471476432.5}}}`
+
+	model := new(TimestampModel)
+	if err := UnmarshalPayload(strings.NewReader(doc), model); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2016, 8, 17, 23, 27, 12, 500000000, time.UTC)
+	if !model.DefaultV.Equal(expected) {
+		t.Fatalf("Was expecting %v, got %v", expected, model.DefaultV)
+	}
+}
+
+func TestTimeFormats_invalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		attributes string
+		err        error
+	}{
+		"date":        {`{"date": "17-08-2016"}`, ErrInvalidTimeFormat},
+		"custom":      {`{"custom": 1471476432}`, ErrInvalidTimeFormat},
+		"rfc3339nano": {`{"nano": "2016-08-17"}`, ErrInvalidRFC3339},
+		"unixmilli":   {`{"milli": "1471476432123"}`, ErrInvalidTime},
+	} {
+		t.Run(name, func(t *testing.T) {
+			doc := `{"data": {"type": "time-formats", "attributes": ` + tc.attributes + `}}`
+
+			err := UnmarshalPayload(strings.NewReader(doc), new(TimeFormats))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Was expecting %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRegisterTimeFormat_invalidName(t *testing.T) {
+	for _, name := range []string{"", "date", "omitempty", "keepzone"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Was expecting RegisterTimeFormat(%q) to panic", name)
+				}
+			}()
+			RegisterTimeFormat(name, time.RFC822)
+		}()
+	}
+}
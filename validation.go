@@ -0,0 +1,412 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// Validation rule names, see the jsonapi-validate struct tag.
+	ruleRequired = "required"
+	ruleMin      = "min"
+	ruleMax      = "max"
+	ruleLen      = "len"
+	ruleMinLen   = "minlen"
+	ruleMaxLen   = "maxlen"
+	ruleRegex    = "regex"
+	ruleEnum     = "enum"
+
+	ruleParamSeparator = "="
+	ruleEnumSeparator  = "|"
+)
+
+// ErrInvalidRule is returned for a jsonapi-validate rule that can't be
+// applied to its struct field: an unknown rule name, a parameter that isn't a
+// number or a pattern, or a rule that doesn't fit the kind of the field.
+// CheckModel reports it wrapped in a *TagError. During unmarshaling it is a
+// mistake in the model rather than in the document, and its error object has
+// the "500 Internal Server Error" status.
+var ErrInvalidRule = errors.New("invalid validation rule")
+
+// ValidationError is returned when an attribute or relationship violates one
+// of the constraints declared in its jsonapi-validate struct tag. During
+// unmarshaling it is wrapped in a *FieldError carrying the JSON Pointer of the
+// member.
+type ValidationError struct {
+	// Member is the name of the attribute or relationship.
+	Member string
+
+	// Rule is the name of the violated rule, e.g. "min".
+	Rule string
+
+	// Param is the parameter of the violated rule, e.g. "1" for "min=1".
+	Param string
+
+	// Message describes the violation, e.g. "must be at least 1".
+	Message string
+}
+
+// Error implements the `Error` interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Member, e.Message)
+}
+
+// ErrorObject converts the error into a "422 Unprocessable Entity" error
+// object.
+func (e *ValidationError) ErrorObject() *ErrorObject {
+	return &ErrorObject{
+		Title:  "Validation Failed",
+		Detail: e.Error(),
+		Status: "422",
+		Code:   e.Rule,
+	}
+}
+
+// ValidatorFunc checks the value of a struct field for a custom validation
+// rule. value is the field value, with pointers dereferenced, and param is
+// the text following "=" in the rule, if any. A non-nil error marks the value
+// as invalid and its message is used to describe the violation.
+type ValidatorFunc func(value interface{}, param string) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{}
+
+	// validationRulesCache caches the parsed rules of jsonapi-validate tags.
+	validationRulesCache sync.Map
+
+	// regexpCache caches the compiled patterns of "regex" rules.
+	regexpCache sync.Map
+)
+
+// RegisterValidator makes fn available as the rule name in jsonapi-validate
+// struct tags, e.g. RegisterValidator("isbn", checkISBN) enables
+// `jsonapi-validate:"required,isbn"`. It panics if name is empty or is the
+// name of a built-in rule.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	switch name {
+	case "", ruleRequired, ruleMin, ruleMax, ruleLen, ruleMinLen, ruleMaxLen,
+		ruleRegex, ruleEnum:
+		panic(fmt.Sprintf("jsonapi: invalid validator name %q", name))
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	validators[name] = fn
+}
+
+func lookupValidator(name string) (ValidatorFunc, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	fn, ok := validators[name]
+	return fn, ok
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+// parseValidationRules splits a jsonapi-validate tag into its rules. Rules
+// are comma separated; since patterns may contain commas, a "regex" rule
+// consumes the remainder of the tag and must therefore come last.
+func parseValidationRules(tag string) []validationRule {
+	if cached, ok := validationRulesCache.Load(tag); ok {
+		return cached.([]validationRule)
+	}
+
+	var rules []validationRule
+	rest := tag
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, ruleRegex+ruleParamSeparator) {
+			part, rest = rest, ""
+		} else if i := strings.Index(rest, annotationSeperator); i >= 0 {
+			part, rest = rest[:i], rest[i+1:]
+		} else {
+			part, rest = rest, ""
+		}
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		rule := validationRule{name: part}
+		if i := strings.Index(part, ruleParamSeparator); i >= 0 {
+			rule.name, rule.param = part[:i], part[i+1:]
+		}
+		rules = append(rules, rule)
+	}
+
+	validationRulesCache.Store(tag, rules)
+
+	return rules
+}
+
+// validateMember checks the constraints declared on structField against
+// value, the already unmarshaled field value. present reports whether the
+// member was present in the document with a non-null value; only the
+// "required" rule is checked for absent members.
+func validateMember(structField reflect.StructField, name string, value reflect.Value, present bool) error {
+	tag, ok := structField.Tag.Lookup(annotationValidate)
+	if !ok {
+		return nil
+	}
+
+	for _, rule := range parseValidationRules(tag) {
+		if rule.name == ruleRequired {
+			if !present {
+				return &ValidationError{Member: name, Rule: rule.name, Message: "is required"}
+			}
+			continue
+		}
+
+		if !present {
+			continue
+		}
+
+		v := reflect.Indirect(value)
+		if !v.IsValid() {
+			continue
+		}
+
+		msg, err := checkRule(rule, v)
+		if err != nil {
+			return &ruleError{field: structField.Name, rule: rule.name, err: err}
+		}
+		if msg != "" {
+			return &ValidationError{
+				Member:  name,
+				Rule:    rule.name,
+				Param:   rule.param,
+				Message: msg,
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRule evaluates a single rule against v. It returns a non-empty message
+// describing the violation if v is invalid, or an error if the rule itself
+// can't be applied to v.
+func checkRule(rule validationRule, v reflect.Value) (string, error) {
+	switch rule.name {
+	case ruleMin, ruleMax:
+		bound, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil {
+			return "", err
+		}
+		n, err := numericValue(v)
+		if err != nil {
+			return "", err
+		}
+		if rule.name == ruleMin && n < bound {
+			return fmt.Sprintf("must be at least %s", rule.param), nil
+		}
+		if rule.name == ruleMax && n > bound {
+			return fmt.Sprintf("must be at most %s", rule.param), nil
+		}
+	case ruleLen, ruleMinLen, ruleMaxLen:
+		bound, err := strconv.Atoi(rule.param)
+		if err != nil {
+			return "", err
+		}
+		n, err := lengthOf(v)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case rule.name == ruleLen && n != bound:
+			return fmt.Sprintf("must have a length of %d", bound), nil
+		case rule.name == ruleMinLen && n < bound:
+			return fmt.Sprintf("must have a length of at least %d", bound), nil
+		case rule.name == ruleMaxLen && n > bound:
+			return fmt.Sprintf("must have a length of at most %d", bound), nil
+		}
+	case ruleRegex:
+		re, err := compileRegexp(rule.param)
+		if err != nil {
+			return "", err
+		}
+		if v.Kind() != reflect.String {
+			return "", ErrInvalidType
+		}
+		if !re.MatchString(v.String()) {
+			return fmt.Sprintf("must match the pattern %s", rule.param), nil
+		}
+	case ruleEnum:
+		allowed := strings.Split(rule.param, ruleEnumSeparator)
+		s := fmt.Sprint(v.Interface())
+		for _, a := range allowed {
+			if s == a {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", ")), nil
+	default:
+		fn, ok := lookupValidator(rule.name)
+		if !ok {
+			return "", errors.New("unknown rule")
+		}
+		if err := fn(v.Interface(), rule.param); err != nil {
+			return err.Error(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// ruleError is returned when a rule can't be applied to the value of a
+// struct field.
+type ruleError struct {
+	field string
+	rule  string
+	err   error
+}
+
+// Error implements the `Error` interface.
+func (e *ruleError) Error() string {
+	return fmt.Sprintf("jsonapi: invalid %s rule %q on struct field `%s`: %v",
+		annotationValidate, e.rule, e.field, e.err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ruleError) Unwrap() error {
+	return e.err
+}
+
+// Is makes the error match ErrInvalidRule.
+func (e *ruleError) Is(target error) bool {
+	return target == ErrInvalidRule
+}
+
+// ErrorObject converts the error into a "500 Internal Server Error" error
+// object, which doesn't disclose the model.
+func (e *ruleError) ErrorObject() *ErrorObject {
+	return &ErrorObject{
+		Title:  "Internal Server Error",
+		Status: "500",
+	}
+}
+
+// checkValidationRules returns an error wrapping ErrInvalidRule for the first
+// rule of the jsonapi-validate tag that can't be applied to a field of type
+// t.
+func checkValidationRules(tag string, t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, rule := range parseValidationRules(tag) {
+		if err := checkRuleType(rule, t); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidRule, rule.name, err)
+		}
+	}
+
+	return nil
+}
+
+// checkRuleType returns an error if checkRule can't apply rule to values of
+// type t.
+func checkRuleType(rule validationRule, t reflect.Type) error {
+	switch rule.name {
+	case ruleRequired:
+		if rule.param != "" {
+			return errors.New("takes no parameter")
+		}
+	case ruleMin, ruleMax:
+		if _, err := strconv.ParseFloat(rule.param, 64); err != nil {
+			return errors.New("the parameter must be a number")
+		}
+		if _, err := numericValue(reflect.Zero(t)); err != nil {
+			return fmt.Errorf("does not apply to %v", t)
+		}
+	case ruleLen, ruleMinLen, ruleMaxLen:
+		if n, err := strconv.Atoi(rule.param); err != nil || n < 0 {
+			return errors.New("the parameter must be a non-negative integer")
+		}
+		if _, err := lengthOf(reflect.Zero(t)); err != nil {
+			return fmt.Errorf("does not apply to %v", t)
+		}
+	case ruleRegex:
+		if _, err := compileRegexp(rule.param); err != nil {
+			return err
+		}
+		if t.Kind() != reflect.String {
+			return fmt.Errorf("does not apply to %v", t)
+		}
+	case ruleEnum:
+		if rule.param == "" {
+			return errors.New("the parameter must list the allowed values")
+		}
+	default:
+		if _, ok := lookupValidator(rule.name); !ok {
+			return errors.New("unknown rule")
+		}
+	}
+
+	return nil
+}
+
+func numericValue(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	default:
+		return 0, ErrUnknownFieldNumberType
+	}
+}
+
+func lengthOf(v reflect.Value) (int, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), nil
+	default:
+		return 0, ErrInvalidType
+	}
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexpCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+
+	return re, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 Company.info B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type ValidatedArticle struct {
+	ID       string   `jsonapi:"primary,articles"`
+	Title    string   `jsonapi:"attr,title" jsonapi-validate:"required,minlen=3,maxlen=10"`
+	Rating   *int     `jsonapi:"attr,rating" jsonapi-validate:"min=1,max=5"`
+	Status   string   `jsonapi:"attr,status" jsonapi-validate:"enum=draft|published"`
+	Slug     string   `jsonapi:"attr,slug" jsonapi-validate:"regex=^[a-z]+(-[a-z]+){0,3}$"`
+	Code     string   `jsonapi:"attr,code" jsonapi-validate:"len=2,uppercase"`
+	Author   *Comment `jsonapi:"relation,author" jsonapi-validate:"required"`
+	Comments []*Post  `jsonapi:"relation,comments" jsonapi-validate:"maxlen=1"`
+}
+
+func init() {
+	RegisterValidator("uppercase", func(value interface{}, _ string) error {
+		if s := value.(string); s != strings.ToUpper(s) {
+			return errors.New("must be upper case")
+		}
+		return nil
+	})
+}
+
+func validatedArticlePayload(attrs, rels map[string]interface{}) *bytes.Reader {
+	data, _ := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":          "articles",
+			"id":            "1",
+			"attributes":    attrs,
+			"relationships": rels,
+		},
+	})
+	return bytes.NewReader(data)
+}
+
+func validAuthor() map[string]interface{} {
+	return map[string]interface{}{
+		"author": map[string]interface{}{
+			"data": map[string]interface{}{"type": "comments", "id": "1"},
+		},
+	}
+}
+
+func TestUnmarshalPayload_validation(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		attrs   map[string]interface{}
+		rels    map[string]interface{}
+		pointer string
+		rule    string
+	}{
+		{
+			desc:  "valid",
+			attrs: map[string]interface{}{"title": "Hello", "rating": 3, "status": "draft", "slug": "hello-world", "code": "NL"},
+			rels:  validAuthor(),
+		},
+		{
+			desc:    "required_attribute",
+			attrs:   map[string]interface{}{},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/title",
+			rule:    "required",
+		},
+		{
+			desc:    "minlen",
+			attrs:   map[string]interface{}{"title": "Hi"},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/title",
+			rule:    "minlen",
+		},
+		{
+			desc:    "max",
+			attrs:   map[string]interface{}{"title": "Hello", "rating": 6},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/rating",
+			rule:    "max",
+		},
+		{
+			desc:    "enum",
+			attrs:   map[string]interface{}{"title": "Hello", "status": "deleted"},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/status",
+			rule:    "enum",
+		},
+		{
+			desc:    "regex",
+			attrs:   map[string]interface{}{"title": "Hello", "slug": "Hello World"},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/slug",
+			rule:    "regex",
+		},
+		{
+			desc:    "custom",
+			attrs:   map[string]interface{}{"title": "Hello", "code": "nl"},
+			rels:    validAuthor(),
+			pointer: "/data/attributes/code",
+			rule:    "uppercase",
+		},
+		{
+			desc:    "required_relation",
+			attrs:   map[string]interface{}{"title": "Hello"},
+			rels:    map[string]interface{}{"author": map[string]interface{}{"data": nil}},
+			pointer: "/data/relationships/author",
+			rule:    "required",
+		},
+		{
+			desc:  "maxlen_relation",
+			attrs: map[string]interface{}{"title": "Hello"},
+			rels: map[string]interface{}{
+				"author": validAuthor()["author"],
+				"comments": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "posts", "id": "1"},
+						map[string]interface{}{"type": "posts", "id": "2"},
+					},
+				},
+			},
+			pointer: "/data/relationships/comments",
+			rule:    "maxlen",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := UnmarshalPayload(validatedArticlePayload(tc.attrs, tc.rels), new(ValidatedArticle))
+			if tc.pointer == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var ferr *FieldError
+			if !errors.As(err, &ferr) {
+				t.Fatalf("Was expecting a *FieldError, got %v", err)
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Was expecting a *ValidationError, got %v", err)
+			}
+
+			obj := ferr.ErrorObject()
+			if e, a := tc.pointer, obj.Source.Pointer; e != a {
+				t.Fatalf("Was expecting pointer %s, got %s", e, a)
+			}
+			if e, a := tc.rule, obj.Code; e != a {
+				t.Fatalf("Was expecting code %s, got %s", e, a)
+			}
+			if e, a := "422", obj.Status; e != a {
+				t.Fatalf("Was expecting status %s, got %s", e, a)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPayload_validationCollectErrors(t *testing.T) {
+	attrs := map[string]interface{}{"rating": 0, "status": "deleted"}
+
+	err := UnmarshalPayload(validatedArticlePayload(attrs, nil), new(ValidatedArticle), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var got []string
+	for _, obj := range merr.ErrorObjects() {
+		got = append(got, fmt.Sprintf("%s:%s", obj.Source.Pointer, obj.Code))
+	}
+	expected := []string{
+		"/data/attributes/title:required",
+		"/data/attributes/rating:min",
+		"/data/attributes/status:enum",
+		"/data/relationships/author:required",
+	}
+	if strings.Join(expected, " ") != strings.Join(got, " ") {
+		t.Fatalf("Was expecting %v, got %v", expected, got)
+	}
+}
+
+func TestUnmarshalPayload_validationForUpdate(t *testing.T) {
+	// A partial update leaves out the required title and author
+	attrs := map[string]interface{}{"rating": 4}
+	if err := UnmarshalPayload(validatedArticlePayload(attrs, nil), new(ValidatedArticle), ForUpdate()); err != nil {
+		t.Fatal(err)
+	}
+
+	// but may not clear them
+	attrs = map[string]interface{}{"title": nil}
+	rels := map[string]interface{}{"author": map[string]interface{}{"data": nil}}
+	err := UnmarshalPayload(validatedArticlePayload(attrs, rels), new(ValidatedArticle), ForUpdate(), CollectErrors())
+
+	var merr MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Was expecting a MultiError, got %v", err)
+	}
+
+	var got []string
+	for _, obj := range merr.ErrorObjects() {
+		got = append(got, fmt.Sprintf("%s:%s", obj.Source.Pointer, obj.Code))
+	}
+	expected := []string{
+		"/data/attributes/title:required",
+		"/data/relationships/author:required",
+	}
+	if strings.Join(expected, " ") != strings.Join(got, " ") {
+		t.Fatalf("Was expecting %v, got %v", expected, got)
+	}
+
+	// Create requests must still include them
+	err = UnmarshalPayload(validatedArticlePayload(map[string]interface{}{"rating": 4}, nil), new(ValidatedArticle), ForCreate())
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Rule != ruleRequired {
+		t.Fatalf("Was expecting a required violation, got %v", err)
+	}
+}
+
+func TestUnmarshalPayload_invalidValidationRule(t *testing.T) {
+	type badRule struct {
+		ID    string `jsonapi:"primary,bad-rules"`
+		Title string `jsonapi:"attr,title" jsonapi-validate:"min=3"`
+	}
+	data := `{"data":{"type":"bad-rules","attributes":{"title":"abc"}}}`
+
+	err := UnmarshalPayload(strings.NewReader(data), new(badRule))
+	if err == nil {
+		t.Fatal("Was expecting an error for a numeric rule on a string field")
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		t.Fatal("Was not expecting a misconfigured rule to be reported as a validation error")
+	}
+	if !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("Was expecting ErrInvalidRule, got %v", err)
+	}
+
+	var ferr *FieldError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Was expecting a *FieldError, got %v", err)
+	}
+	if obj := ferr.ErrorObject(); obj.Status != "500" || obj.Detail != "" {
+		t.Fatalf("Was expecting a server error without details, got %+v", obj)
+	}
+}
+
+func TestRegisterValidator_builtinName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Was expecting a panic when overriding a built-in rule")
+		}
+	}()
+
+	RegisterValidator("required", func(interface{}, string) error { return nil })
+}